@@ -31,19 +31,54 @@ export interface DiffMessage {
 export interface ChannelInfo {
   channel: number  // DMX channel 1–512
   param: string    // mapped parameter name
+  value: number    // DMX value 0–255, after the target's transform
+  width: 8 | 16    // 16 for both channels of a coarse/fine pair
+  fine: boolean    // fine channel of a 16-bit target
+}
+
+export interface ParkedChannel {
+  channel: number  // DMX channel 1–512
   value: number    // DMX value 0–255
 }
 
+export type Delivery = 'multicast' | 'unicast' | 'both'
+
+export type OutputProtocol = 'e131' | 'artnet' | 'ddp' | 'enttec-pro' | 'open-dmx'
+
 export interface UniverseStatus {
   label: string
   device_ip: string
-  type: 'wled' | 'gateway'
+  type: 'wled' | 'gateway' | 'usb'
+  protocol: OutputProtocol
+  delivery: Delivery
+  unicast: string[]  // resolved unicast destinations
   online: boolean
   channels: ChannelInfo[]
+  parked: ParkedChannel[]  // channels pinned via /api/park
 }
 
 export type EmitterState = 'connected' | 'idle' | 'disconnected'
 
+/** Per-driver output counters (server/output/output.go Stats) */
+export interface OutputStats {
+  name: string
+  frames: number
+  errors: number
+  last_error: string
+  last_error_at: number  // unix ms, 0 if none
+}
+
+/** External sACN source merged into output (server/e131/receiver.go Source) */
+export interface InputSource {
+  universe: number  // sACN universe the source was heard on
+  cid: string
+  name: string
+  priority: number
+  last_seen: number // unix ms
+}
+
+export type MergePolicy = 'htp' | 'ltp' | 'priority'
+
 /** Connection and universe health */
 export interface StatusMessage {
   type: 'status'
@@ -51,9 +86,90 @@ export interface StatusMessage {
   emitter_last_seen: number
   blackout: boolean
   universes: Record<number, UniverseStatus>
+  outputs: OutputStats[]
+  inputs: InputSource[]
+  masters: MasterLevels
+  held_scene: string  // name of the held scene, '' when live
+  cues: Record<string, CueStatus>
+  failsafe: FailsafeStatus
+  emitters: EmitterStatus[]
+  receiver: ReceiverStats
+  osc: OSCStats
+}
+
+/** OSC input counters (server/osc/receiver.go Stats) */
+export interface OSCStats {
+  messages: number
+  bundles: number
+  ignored: number  // outside the prefix, or without a value
+  errors: number   // malformed datagrams
+}
+
+/** Emitter packet counters (server/udp/stream.go Stats) */
+export interface ReceiverStats {
+  packets: number    // after reassembly
+  keyframes: number  // full-state packets with a seq
+  deltas: number
+  resyncs: number    // sequence gaps that broke the delta chain
+  dropped: number    // deltas discarded until the next keyframe
+  chunks: number     // datagrams carrying part of a frame
+  incomplete: number // chunked frames discarded after a timeout
+  errors: number     // datagrams that failed to decode
+}
+
+/** One emitter being played back (server/interp/mixer.go EmitterStatus) */
+export interface EmitterStatus {
+  source: string      // "ip:port"
+  label?: string
+  session_id: string
+  namespace?: string  // prefix of the emitter's parameters
+  priority: number
+  state: EmitterState
+  last_seen: number   // unix ms
+  parameters: number  // parameters in the last packet
+  late: number        // packets dropped as older than the last accepted
+  stale: number       // packets dropped as delivered too late
+  clock_resets: number
+}
+
+/** Emitter-loss failsafe — see emitter.failsafe in config */
+export interface FailsafeStatus {
+  action: 'hold' | 'blackout' | 'scene'
+  scene?: string   // for action "scene"
+  active: boolean  // tripped: the emitter is lost
+}
+
+/** A cue list's playback position */
+export interface CueStatus {
+  cue: number      // 1-based, 0 before the first GO
+  label: string
+  count: number
+  running: boolean // a delay, fade or follow is in progress
+  paused: boolean
+}
+
+export interface MasterLevels {
+  grand: number                       // 0–1
+  submasters: Record<string, number>  // 0–1 per configured sub-master
 }
 
-export type ServerMessage = SessionMessage | StateMessage | DiffMessage | StatusMessage
+/** A stored look — GET /api/scenes */
+export interface Scene {
+  name: string
+  kind: 'state' | 'dmx'
+  state?: Record<string, number>    // parameter values, kind "state"
+  dmx?: Record<number, number[]>    // universe → channel values, kind "dmx"
+  created: number                   // unix ms
+}
+
+/** A command this client sent was rejected */
+export interface ErrorMessage {
+  type: 'error'
+  command: string  // the rejected message's type, e.g. "master"
+  error: string
+}
+
+export type ServerMessage = SessionMessage | StateMessage | DiffMessage | StatusMessage | ErrorMessage
 
 // ─── UI → Server ──────────────────────────────────────────────────────────────
 
@@ -62,18 +178,59 @@ export interface Patch {
   label: string
   startAddress: number
   channels?: string[]  // only for fixtureKey === "manual"
+  defaults?: Record<string, number>  // channel name → DMX value held when unmapped; overrides the fixture
+}
+
+export interface ArtNetAddress {
+  net: number       // 0–127
+  subnet: number    // 0–15
+  universe: number  // 0–15
+  broadcast?: string
 }
 
 export interface UniverseConfig {
   device_ip: string
-  type: 'wled' | 'gateway'
+  type: 'wled' | 'gateway' | 'usb'
   label: string
+  protocol?: OutputProtocol // default 'e131'; 'artnet' only for gateways, 'ddp' only for wled,
+                            // 'enttec-pro' / 'open-dmx' only for usb
+  artnet?: ArtNetAddress    // default derived from the universe number
+  serial?: { device: string } // USB-DMX serial device, e.g. '/dev/ttyUSB0'
+  merge?: MergePolicy       // merge sACN input into this universe; omit to disable
+  input_universe?: number   // sACN universe to merge, default the same number
+  delivery?: Delivery       // default 'multicast' (broadcast for Art-Net)
+  destinations?: string[]   // extra unicast IPs
+  priority?: number         // E1.31 priority 1–200, default 100
+  sync?: boolean  // opt in to E1.31 synchronization on e131.sync_universe
   patches?: Patch[]
 }
 
+export type Curve = 'linear' | 'square' | 'gamma' | 'scurve' | 'lut'
+
+/** A parameter target. The value passes through curve, then invert, then min–max. */
 export interface ParameterConfig {
   universe: number
-  channel: number  // DMX channel 1–512
+  channel: number        // DMX channel 1–512 (coarse channel for 16-bit)
+  width?: 8 | 16         // default 8
+  fine_channel?: number  // 16-bit only, default channel + 1
+  min?: number           // output range, normalised 0–1; default 0
+  max?: number           // default 1
+  invert?: boolean
+  curve?: Curve          // default 'linear'
+  gamma?: number         // 'gamma' exponent, default 2.2
+  lut?: number[]         // 'lut' outputs 0–1 at evenly spaced inputs
+}
+
+export type ColorModel = 'hsv' | 'rgb' | 'cct'
+
+/** Virtual colour parameter driving one patch's colour channels */
+export interface ColorParamConfig {
+  universe: number
+  patch: string                  // patch label in the universe
+  model: ColorModel
+  inputs: Record<string, string> // component ('hue', 'red', 'kelvin', 'value', ...) → emitter parameter
+  min_kelvin?: number            // 'cct' only, default 2700
+  max_kelvin?: number            // 'cct' only, default 6500
 }
 
 /** Update universe and parameter mapping */
@@ -94,9 +251,28 @@ export interface BlackoutMessage {
   type: 'blackout'
 }
 
+/** Activate blackout instantly, cutting any blackout fade */
+export interface EStopMessage {
+  type: 'estop'
+}
+
+/** Set the grand master (no submaster) or a sub-master level, 0–1 */
+export interface MasterMessage {
+  type: 'master'
+  submaster?: string
+  level: number
+}
+
+/** GO, BACK or PAUSE a cue list */
+export interface CueMessage {
+  type: 'cue'
+  list: string
+  command: 'go' | 'back' | 'pause'
+}
+
 /** Reset from blackout — resume normal operation */
 export interface ResetMessage {
   type: 'reset'
 }
 
-export type UIMessage = SetConfigMessage | HotkeyMessage | BlackoutMessage | ResetMessage
+export type UIMessage = SetConfigMessage | HotkeyMessage | BlackoutMessage | EStopMessage | MasterMessage | CueMessage | ResetMessage
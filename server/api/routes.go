@@ -2,14 +2,20 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/footgunz/penumbra/config"
 	"github.com/footgunz/penumbra/fixtures"
+	"github.com/footgunz/penumbra/output"
+	"github.com/footgunz/penumbra/scene"
 	"github.com/footgunz/penumbra/ui"
 	"github.com/footgunz/penumbra/ws"
 )
@@ -47,7 +53,7 @@ h1{font-size:1rem;letter-spacing:.15em;text-transform:uppercase;color:#666;margi
 <script>
 var blackout=false,ws;
 function toggle(){
-  fetch('/api/'+(blackout?'reset':'blackout'),{method:'POST'}).catch(function(){});
+  fetch('/api/'+(blackout?'reset':'estop'),{method:'POST'}).catch(function(){});
 }
 function render(){
   document.body.className=blackout?'blackout':'armed';
@@ -71,26 +77,54 @@ connect();
 </body>
 </html>`
 
+// Controls are the runtime controls the API exposes besides config.
+type Controls struct {
+	Parking *output.Parking
+	Masters *output.Masters
+	Scenes  *scene.Player
+	Cues    *scene.Cues
+}
+
 // NewRouter wires HTTP routes and returns an *http.Server ready for ListenAndServe.
 // onConfigUpdate is called after a successful POST /api/config (may be nil).
 //
 // Routes:
-//   GET  /ws             → WebSocket upgrade
-//   GET  /api/config     → Return current config as JSON
-//   POST /api/config     → Update universe/parameter mapping and persist
-//   POST /api/blackout   → Enter blackout mode
-//   POST /api/reset      → Exit blackout mode
-//   GET  /api/fixtures   → List all fixtures
-//   POST /api/fixtures   → Add a fixture (in-memory only)
-//   GET  /               → Serve embedded Vite/React PWA (ui/dist)
-func NewRouter(hub *ws.Hub, cfg *config.Config, fixtureStore *fixtures.Store, port int, onConfigUpdate func(*config.Config)) *http.Server {
+//
+//	GET  /ws             → WebSocket upgrade
+//	GET  /api/config     → Return current config as JSON
+//	POST /api/config     → Update universe/parameter mapping and persist
+//	POST /api/blackout   → Enter blackout mode, fading out
+//	POST /api/estop      → Enter blackout mode instantly, cutting any fade
+//	POST /api/reset      → Exit blackout mode
+//	GET  /api/fixtures   → List all fixtures
+//	POST /api/fixtures   → Add a fixture (in-memory only)
+//	GET  /api/park       → List parked channels
+//	POST /api/park       → Park a channel at a value (runtime only)
+//	DELETE /api/park     → Unpark ?universe=N&channel=M, or everything
+//	GET  /api/masters    → Grand master and sub-master levels
+//	POST /api/masters    → Set grand master and/or sub-master levels (runtime only)
+//	GET  /api/scenes     → List stored scenes
+//	POST /api/scenes     → Capture a scene from state or DMX, or store one given
+//	GET  /api/scenes/{name}        → Return one scene
+//	DELETE /api/scenes/{name}      → Delete a scene
+//	POST /api/scenes/{name}/recall → Fade to a scene, optionally holding it
+//	GET  /api/hold       → Name of the held scene
+//	DELETE /api/hold     → Release the held scene, fading back to live (?fade_ms=N)
+//	GET  /api/cues       → Position of every cue list
+//	POST /api/cues/{list}/{command} → GO, BACK or PAUSE a cue list
+//	GET  /               → Serve embedded Vite/React PWA (ui/dist)
+func NewRouter(hub *ws.Hub, cfg *config.Config, fixtureStore *fixtures.Store, controls Controls, port int, onConfigUpdate func(*config.Config)) *http.Server {
 	mux := http.NewServeMux()
 
 	// WebSocket endpoint
 	mux.HandleFunc("/ws", hub.ServeWS)
 
-	// Config endpoint — GET returns current config, POST updates it
+	// Config endpoint — GET returns current config, POST updates it. An
+	// update is validated on a copy and only swapped in once it passes.
+	var configMu sync.Mutex
 	mux.HandleFunc("/api/config", func(w http.ResponseWriter, r *http.Request) {
+		configMu.Lock()
+		defer configMu.Unlock()
 		switch r.Method {
 		case http.MethodGet:
 			data, err := json.MarshalIndent(cfg, "", "  ")
@@ -115,33 +149,35 @@ func NewRouter(hub *ws.Hub, cfg *config.Config, fixtureStore *fixtures.Store, po
 				http.Error(w, "invalid JSON", http.StatusBadRequest)
 				return
 			}
+			next := *cfg
 			if update.Universes != nil {
-				cfg.Universes = update.Universes
+				next.Universes = update.Universes
 			}
 			if update.Parameters != nil {
-				cfg.Parameters = update.Parameters
-			}
-			// Validate patches in each universe
-			for uid, u := range cfg.Universes {
-				if len(u.Patches) > 0 {
-					resolver := func(key string) int {
-						f, ok := fixtureStore.Get(key)
-						if !ok {
-							return 0
-						}
-						return f.ChannelCount
-					}
-					if err := config.ValidatePatches(u.Patches, resolver); err != nil {
-						http.Error(w, fmt.Sprintf("universe %d: %v", uid, err), http.StatusBadRequest)
-						return
-					}
-				}
+				next.Parameters = update.Parameters
+			}
+			if err := next.ValidateUniverses(fixtureStore.ChannelCount, fixtureStore.Fixture); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := next.ValidateSync(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := next.ValidateSubmasters(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
 			}
-			if err := cfg.Save(); err != nil {
+			if err := next.ValidateColors(fixtureStore.Fixture); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := next.Save(); err != nil {
 				log.Printf("api: config save: %v", err)
 				http.Error(w, "save error", http.StatusInternalServerError)
 				return
 			}
+			cfg.Universes, cfg.Parameters = next.Universes, next.Parameters
 			log.Printf("api: config updated (%d universes, %d parameters)",
 				len(cfg.Universes), len(cfg.Parameters))
 			hub.BroadcastStatus()
@@ -168,6 +204,16 @@ func NewRouter(hub *ws.Hub, cfg *config.Config, fixtureStore *fixtures.Store, po
 		w.Write([]byte(`{"ok":true,"blackout":true}`))
 	})
 
+	mux.HandleFunc("/api/estop", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		hub.EStop()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"blackout":true}`))
+	})
+
 	mux.HandleFunc("/api/reset", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -197,7 +243,7 @@ func NewRouter(hub *ws.Hub, cfg *config.Config, fixtureStore *fixtures.Store, po
 				return
 			}
 			var req struct {
-				Key     string          `json:"key"`
+				Key     string           `json:"key"`
 				Fixture fixtures.Fixture `json:"fixture"`
 			}
 			if err := json.Unmarshal(body, &req); err != nil {
@@ -226,6 +272,339 @@ func NewRouter(hub *ws.Hub, cfg *config.Config, fixtureStore *fixtures.Store, po
 		}
 	})
 
+	// Park endpoints — pin channels regardless of emitter state (runtime only)
+	mux.HandleFunc("/api/park", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			data, err := json.Marshal(controls.Parking.Parked())
+			if err != nil {
+				http.Error(w, "marshal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+
+		case http.MethodPost:
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "read error", http.StatusBadRequest)
+				return
+			}
+			var req output.ParkedChannel
+			if err := json.Unmarshal(body, &req); err != nil {
+				http.Error(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
+			if _, ok := cfg.Universes[req.Universe]; !ok {
+				http.Error(w, fmt.Sprintf("universe %d is not configured", req.Universe), http.StatusBadRequest)
+				return
+			}
+			if req.Channel < 1 || req.Channel > 512 || req.Value < 0 || req.Value > 255 {
+				http.Error(w, "channel must be 1-512 and value 0-255", http.StatusBadRequest)
+				return
+			}
+			controls.Parking.Park(req.Universe, req.Channel, byte(req.Value))
+			log.Printf("api: parked universe %d channel %d at %d", req.Universe, req.Channel, req.Value)
+			hub.BroadcastStatus()
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+
+		case http.MethodDelete:
+			q := r.URL.Query()
+			if q.Get("universe") == "" && q.Get("channel") == "" {
+				controls.Parking.UnparkAll()
+				log.Printf("api: unparked all channels")
+			} else {
+				universe, err1 := strconv.Atoi(q.Get("universe"))
+				channel, err2 := strconv.Atoi(q.Get("channel"))
+				if err1 != nil || err2 != nil {
+					http.Error(w, "universe and channel must be integers", http.StatusBadRequest)
+					return
+				}
+				if !controls.Parking.Unpark(universe, channel) {
+					http.Error(w, "channel is not parked", http.StatusNotFound)
+					return
+				}
+				log.Printf("api: unparked universe %d channel %d", universe, channel)
+			}
+			hub.BroadcastStatus()
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Masters — levels scale intensity at output time
+	mux.HandleFunc("/api/masters", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			// Levels are written below.
+
+		case http.MethodPost:
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "read error", http.StatusBadRequest)
+				return
+			}
+			var req struct {
+				Grand      *float64           `json:"grand"`
+				Submasters map[string]float64 `json:"submasters"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				http.Error(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
+			if req.Grand != nil {
+				if err := controls.Masters.Validate("", *req.Grand); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			for name, level := range req.Submasters {
+				if err := controls.Masters.Validate(name, level); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if req.Grand != nil {
+				controls.Masters.SetGrand(*req.Grand)
+				log.Printf("api: grand master %.0f%%", *req.Grand*100)
+			}
+			for name, level := range req.Submasters {
+				if err := controls.Masters.SetSubmaster(name, level); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				log.Printf("api: submaster %q %.0f%%", name, level*100)
+			}
+			hub.BroadcastStatus()
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := json.Marshal(controls.Masters.Levels())
+		if err != nil {
+			http.Error(w, "marshal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+
+	// Scenes — captured looks stored next to the config
+	mux.HandleFunc("/api/scenes", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			data, err := json.Marshal(controls.Scenes.Store().List())
+			if err != nil {
+				http.Error(w, "marshal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+
+		case http.MethodPost:
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "read error", http.StatusBadRequest)
+				return
+			}
+			var req struct {
+				Name   string             `json:"name"`
+				Source string             `json:"source"`
+				State  map[string]float64 `json:"state"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				http.Error(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
+			var sc scene.Scene
+			switch {
+			case req.State != nil:
+				sc = scene.Scene{Name: req.Name, Kind: scene.KindState, State: req.State, Created: time.Now().UnixMilli()}
+				err = controls.Scenes.Store().Put(sc)
+			case req.Source == scene.KindState || req.Source == scene.KindDMX:
+				sc, err = controls.Scenes.Capture(req.Name, req.Source)
+			default:
+				http.Error(w, `source must be "state" or "dmx", or give a state`, http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Printf("api: scene %q stored (%s)", sc.Name, sc.Kind)
+			data, err := json.Marshal(sc)
+			if err != nil {
+				http.Error(w, "marshal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write(data)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/scenes/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		switch r.Method {
+		case http.MethodGet:
+			sc, ok := controls.Scenes.Store().Get(name)
+			if !ok {
+				http.Error(w, "no such scene", http.StatusNotFound)
+				return
+			}
+			data, err := json.Marshal(sc)
+			if err != nil {
+				http.Error(w, "marshal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+
+		case http.MethodDelete:
+			ok, err := controls.Scenes.Store().Delete(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, "no such scene", http.StatusNotFound)
+				return
+			}
+			log.Printf("api: scene %q deleted", name)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/scenes/{name}/recall", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		name := r.PathValue("name")
+		var req struct {
+			FadeMs int  `json:"fade_ms"`
+			Hold   bool `json:"hold"`
+		}
+		// The body is optional: no body recalls instantly without holding.
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if req.FadeMs < 0 {
+			http.Error(w, "fade_ms must not be negative", http.StatusBadRequest)
+			return
+		}
+		err := controls.Scenes.Recall(name, time.Duration(req.FadeMs)*time.Millisecond, req.Hold)
+		switch {
+		case errors.Is(err, scene.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, scene.ErrBlackout):
+			http.Error(w, "blackout is active; recall with hold to show the scene on reset", http.StatusConflict)
+			return
+		case err != nil:
+			log.Printf("api: scene %q recall: %v", name, err)
+			http.Error(w, "recall error", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("api: scene %q recalled (fade %dms, hold %t)", name, req.FadeMs, req.Hold)
+		hub.BroadcastStatus()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	// Hold — a held scene replaces live output until released
+	mux.HandleFunc("/api/hold", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			data, err := json.Marshal(map[string]string{"scene": controls.Scenes.Held()})
+			if err != nil {
+				http.Error(w, "marshal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+
+		case http.MethodDelete:
+			fadeMs := 0
+			if v := r.URL.Query().Get("fade_ms"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					http.Error(w, "fade_ms must be a non-negative integer", http.StatusBadRequest)
+					return
+				}
+				fadeMs = n
+			}
+			if !controls.Scenes.Release(time.Duration(fadeMs) * time.Millisecond) {
+				http.Error(w, "no scene is held", http.StatusNotFound)
+				return
+			}
+			log.Printf("api: held scene released (fade %dms)", fadeMs)
+			hub.BroadcastStatus()
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Cues — cue lists from config, played back over the live output
+	mux.HandleFunc("/api/cues", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := json.Marshal(controls.Cues.Status())
+		if err != nil {
+			http.Error(w, "marshal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+
+	mux.HandleFunc("/api/cues/{list}/{command}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		list, command := r.PathValue("list"), r.PathValue("command")
+		err := controls.Cues.Command(list, command)
+		switch {
+		case errors.Is(err, scene.ErrNoList):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, scene.ErrCommand):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("api: cue list %q %s", list, command)
+		hub.BroadcastStatus()
+		data, err := json.Marshal(controls.Cues.Status()[list])
+		if err != nil {
+			http.Error(w, "marshal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+
 	// E-Stop page — standalone mobile-friendly big red button
 	mux.HandleFunc("/estop", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
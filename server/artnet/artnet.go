@@ -0,0 +1,111 @@
+// Package artnet sends DMX frames to Art-Net 4 nodes as ArtDMX packets.
+package artnet
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/dmx"
+	"github.com/footgunz/penumbra/output"
+)
+
+const (
+	Port            = 6454
+	HeaderSize      = 18
+	ProtocolVersion = 14
+	opDMX           = 0x5000
+)
+
+var artNetID = []byte{'A', 'r', 't', '-', 'N', 'e', 't', 0x00}
+
+func init() {
+	output.Register("artnet", func(cfg *config.Config, _ config.ChannelCountResolver) (output.Driver, error) {
+		return NewDriver(), nil
+	})
+}
+
+// Driver sends ArtDMX packets for every universe whose protocol is "artnet".
+// It keeps one long-lived socket per destination address. Art-Net nodes
+// generally expect a refresh at least every 4 seconds, which the output
+// keep-alive covers.
+type Driver struct {
+	sequences map[int]uint8
+	udp       *output.UDPSender
+}
+
+// NewDriver creates an Art-Net driver.
+func NewDriver() *Driver {
+	return &Driver{
+		sequences: make(map[int]uint8),
+		udp:       output.NewUDPSender("udp4", Port),
+	}
+}
+
+// Handles reports whether u is sent over Art-Net.
+func (d *Driver) Handles(u config.UniverseConfig) bool {
+	return u.OutputProtocol() == config.ProtocolArtNet
+}
+
+// Send transmits one ArtDMX packet per frame.
+func (d *Driver) Send(frames []output.Frame) error {
+	var errs []error
+	for _, f := range frames {
+		addr := f.Config.ArtNetAddress(f.Universe)
+		pkt := buildDMXPacket(addr.PortAddress(), d.nextSeq(f.Universe), f.Data)
+		for _, dest := range destinations(f.Config, addr) {
+			errs = append(errs, d.udp.Send(dest, pkt))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases the sockets.
+func (d *Driver) Close() error {
+	return d.udp.Close()
+}
+
+// nextSeq returns the next ArtDMX sequence number for universe. Sequence
+// runs 1–255; 0 would tell the node to disable reordering.
+func (d *Driver) nextSeq(universe int) uint8 {
+	seq := d.sequences[universe] + 1
+	if seq == 0 {
+		seq = 1
+	}
+	d.sequences[universe] = seq
+	return seq
+}
+
+// destinations returns every address a universe is sent to. Art-Net has no
+// multicast, so "multicast" delivery uses the broadcast address instead.
+func destinations(u config.UniverseConfig, addr config.ArtNet) []string {
+	var addrs []string
+	if u.Multicast() {
+		addrs = append(addrs, addr.Broadcast)
+	}
+	return append(addrs, u.UnicastTargets()...)
+}
+
+// buildDMXPacket builds an ArtDMX packet carrying a full 512-channel frame.
+func buildDMXPacket(port uint16, seq uint8, data []byte) []byte {
+	length := len(data)
+	if length > dmx.UniverseSize {
+		length = dmx.UniverseSize
+	}
+	if length%2 != 0 {
+		length++ // ArtDMX data length must be even
+	}
+	buf := make([]byte, HeaderSize+length)
+
+	copy(buf[0:], artNetID)                               // ID "Art-Net\0"
+	binary.LittleEndian.PutUint16(buf[8:], opDMX)         // OpCode (little-endian)
+	binary.BigEndian.PutUint16(buf[10:], ProtocolVersion) // ProtVer
+	buf[12] = seq                                         // Sequence
+	buf[13] = 0                                           // Physical input port
+	buf[14] = byte(port & 0xff)                           // SubUni: SubNet << 4 | Universe
+	buf[15] = byte(port>>8) & 0x7f                        // Net
+	binary.BigEndian.PutUint16(buf[16:], uint16(length))  // Length
+	copy(buf[HeaderSize:], data)
+
+	return buf
+}
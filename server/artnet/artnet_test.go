@@ -0,0 +1,53 @@
+package artnet
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/footgunz/penumbra/config"
+)
+
+func TestBuildDMXPacket_Layout(t *testing.T) {
+	data := make([]byte, 512)
+	data[0] = 0x11
+	data[511] = 0x22
+	port := config.ArtNet{Net: 3, SubNet: 2, Universe: 1}.PortAddress()
+
+	pkt := buildDMXPacket(port, 9, data)
+
+	if len(pkt) != HeaderSize+512 {
+		t.Fatalf("packet length = %d, want %d", len(pkt), HeaderSize+512)
+	}
+	if string(pkt[0:8]) != "Art-Net\x00" {
+		t.Errorf("ID = %q", pkt[0:8])
+	}
+	if pkt[8] != 0x00 || pkt[9] != 0x50 {
+		t.Errorf("OpCode bytes = %#x %#x, want 0x00 0x50", pkt[8], pkt[9])
+	}
+	if got := binary.BigEndian.Uint16(pkt[10:]); got != ProtocolVersion {
+		t.Errorf("ProtVer = %d, want %d", got, ProtocolVersion)
+	}
+	if pkt[12] != 9 {
+		t.Errorf("Sequence = %d, want 9", pkt[12])
+	}
+	if pkt[14] != 0x21 || pkt[15] != 3 {
+		t.Errorf("SubUni/Net = %#x/%d, want 0x21/3", pkt[14], pkt[15])
+	}
+	if got := binary.BigEndian.Uint16(pkt[16:]); got != 512 {
+		t.Errorf("Length = %d, want 512", got)
+	}
+	if pkt[18] != 0x11 || pkt[len(pkt)-1] != 0x22 {
+		t.Errorf("data misplaced")
+	}
+}
+
+func TestNextSeqSkipsZero(t *testing.T) {
+	d := NewDriver()
+	d.sequences[1] = 254
+	if got := d.nextSeq(1); got != 255 {
+		t.Fatalf("seq = %d, want 255", got)
+	}
+	if got := d.nextSeq(1); got != 1 {
+		t.Fatalf("seq after wrap = %d, want 1", got)
+	}
+}
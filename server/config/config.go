@@ -4,7 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 )
 
 // Config holds universe and parameter mapping.
@@ -13,14 +19,367 @@ type Config struct {
 	Universes     map[int]UniverseConfig     `json:"universes"`
 	Parameters    map[string]ParameterConfig `json:"parameters"`
 	Emitter       EmitterConfig              `json:"emitter"`
+	Output        OutputConfig               `json:"output"`
+	E131          E131Config                 `json:"e131"`
+	Smoothing     SmoothingConfig            `json:"smoothing"`
+	Fades         FadeConfig                 `json:"fades"`
 	BlackoutScene map[string]float64         `json:"blackout_scene"`
+	Submasters    map[string]Submaster       `json:"submasters,omitempty"`
+	Colors        map[string]ColorParam      `json:"colors,omitempty"`
+	Cues          CueConfig                  `json:"cues"`
+	OSC           OSCConfig                  `json:"osc"`
 	path          string
 }
 
-// EmitterConfig holds timeout thresholds for emitter connection state detection.
+// OutputConfig controls how computed DMX frames are sent to devices.
+// Drivers lists the output drivers to enable by registered name; each driver
+// only sends the universes it handles. While a universe is changing it is
+// retransmitted at RefreshHz; once its frame has been static for KeepAliveMs
+// it drops to one packet per KeepAliveMs, which keeps receivers such as WLED
+// from timing out during a held look.
+type OutputConfig struct {
+	Drivers     []string `json:"drivers"`
+	RefreshHz   int      `json:"refresh_hz"`
+	KeepAliveMs int      `json:"keepalive_ms"`
+}
+
+// E131Config holds E1.31 (sACN) sender options.
+// SourceName is the user-facing source name receivers display (max 63 bytes).
+// TerminateOnBlackout makes blackout send the blackout scene once and then
+// terminate every stream, so receivers fall back to their own loss-of-data
+// behaviour.
+// SyncUniverse is the universe that carries E1.31 synchronization packets;
+// 0 disables synchronization for every universe.
+type E131Config struct {
+	SourceName          string `json:"source_name"`
+	TerminateOnBlackout bool   `json:"terminate_on_blackout"`
+	SyncUniverse        int    `json:"sync_universe"`
+}
+
+// OSCConfig enables OSC input on UDP Port, for tools that can't send the
+// msgpack emitter protocol; 0 (the default) disables it. A message under
+// Prefix (default "/penumbra") sets the parameter named by the rest of its
+// address, so "/penumbra/par_front/Dimmer" sets "par_front/Dimmer". The
+// state from each OSC sender is reported under SessionID (default "osc").
+type OSCConfig struct {
+	Port      int    `json:"port,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// AddressPrefix returns Prefix without a trailing slash, defaulting to
+// "/penumbra".
+func (o OSCConfig) AddressPrefix() string {
+	if o.Prefix == "" {
+		return "/penumbra"
+	}
+	return strings.TrimSuffix(o.Prefix, "/")
+}
+
+// Session returns SessionID, defaulting to "osc".
+func (o OSCConfig) Session() string {
+	if o.SessionID == "" {
+		return "osc"
+	}
+	return o.SessionID
+}
+
+// ValidateOSC checks the OSC port and address prefix.
+func ValidateOSC(o OSCConfig) error {
+	if o.Port < 0 || o.Port > 65535 {
+		return fmt.Errorf("osc: port %d out of range", o.Port)
+	}
+	if o.Prefix != "" && !strings.HasPrefix(o.Prefix, "/") {
+		return fmt.Errorf("osc: prefix %q must start with '/'", o.Prefix)
+	}
+	return nil
+}
+
+// SmoothingConfig controls how parameter state is played back between
+// emitter packets. Received states are delayed by JitterMs and placed on the
+// emitter's timeline (packet ts), so irregular arrival doesn't show up as
+// irregular motion; the result is rendered at the output refresh rate.
+// Mode is the smoothing applied to every parameter: "none" steps to each
+// received value, "linear" interpolates between consecutive states and
+// "exponential" eases toward them with time constant TimeConstantMs.
+// Parameters overrides Mode per parameter name, e.g. {"strobe": "none"} keeps
+// a strobe crisp.
+type SmoothingConfig struct {
+	JitterMs       int               `json:"jitter_ms"`
+	Mode           string            `json:"mode"`
+	TimeConstantMs int               `json:"time_constant_ms"`
+	Parameters     map[string]string `json:"parameters,omitempty"`
+}
+
+// FadeConfig sets crossfade times, in milliseconds, for output transitions:
+// into blackout, back out of it on reset, and from one emitter session to
+// the next. 0 switches instantly. An e-stop always cuts without fading.
+type FadeConfig struct {
+	BlackoutMs int `json:"blackout_ms"`
+	ResetMs    int `json:"reset_ms"`
+	SessionMs  int `json:"session_ms"`
+}
+
+// Blackout returns the blackout fade time.
+func (f FadeConfig) Blackout() time.Duration { return time.Duration(f.BlackoutMs) * time.Millisecond }
+
+// Reset returns the reset fade time.
+func (f FadeConfig) Reset() time.Duration { return time.Duration(f.ResetMs) * time.Millisecond }
+
+// Session returns the session-change crossfade time.
+func (f FadeConfig) Session() time.Duration { return time.Duration(f.SessionMs) * time.Millisecond }
+
+// Smoothing modes for SmoothingConfig.
+const (
+	SmoothingNone        = "none"
+	SmoothingLinear      = "linear"
+	SmoothingExponential = "exponential"
+)
+
+// ModeFor returns the smoothing mode for param.
+func (s SmoothingConfig) ModeFor(param string) string {
+	if mode, ok := s.Parameters[param]; ok {
+		return mode
+	}
+	return s.Mode
+}
+
+// ValidateSmoothing checks the smoothing modes and timings.
+func ValidateSmoothing(s SmoothingConfig) error {
+	if s.JitterMs < 0 {
+		return fmt.Errorf("smoothing: jitter_ms must not be negative")
+	}
+	if s.TimeConstantMs < 0 {
+		return fmt.Errorf("smoothing: time_constant_ms must not be negative")
+	}
+	valid := []string{SmoothingNone, SmoothingLinear, SmoothingExponential}
+	if !slices.Contains(valid, s.Mode) {
+		return fmt.Errorf("smoothing: unknown mode %q (want none, linear or exponential)", s.Mode)
+	}
+	for param, mode := range s.Parameters {
+		if !slices.Contains(valid, mode) {
+			return fmt.Errorf("smoothing: parameter %q: unknown mode %q (want none, linear or exponential)", param, mode)
+		}
+	}
+	return nil
+}
+
+// CueConfig holds the cue lists, keyed by name. Each list plays back its own
+// stored scenes and is layered over the live output: Merge "htp" (the
+// default) keeps the higher of the live and cue values per channel, "ltp"
+// lets the cue replace the live value.
+type CueConfig struct {
+	Merge string           `json:"merge,omitempty"`
+	Lists map[string][]Cue `json:"lists,omitempty"`
+}
+
+// Cue is one step of a cue list. On GO the scene named Scene starts after
+// DelayMs; channels rising to it fade over FadeInMs and channels falling
+// fade over FadeOutMs. If FollowMs is set, the next cue is taken
+// automatically that long after this one completes.
+type Cue struct {
+	Label     string `json:"label,omitempty"`
+	Scene     string `json:"scene"`
+	FadeInMs  int    `json:"fade_in_ms,omitempty"`
+	FadeOutMs int    `json:"fade_out_ms,omitempty"`
+	DelayMs   int    `json:"delay_ms,omitempty"`
+	FollowMs  *int   `json:"follow_ms,omitempty"`
+}
+
+// FadeIn returns the cue's fade-in time.
+func (c Cue) FadeIn() time.Duration { return time.Duration(c.FadeInMs) * time.Millisecond }
+
+// FadeOut returns the cue's fade-out time.
+func (c Cue) FadeOut() time.Duration { return time.Duration(c.FadeOutMs) * time.Millisecond }
+
+// Delay returns the time between GO and the start of the cue's fades.
+func (c Cue) Delay() time.Duration { return time.Duration(c.DelayMs) * time.Millisecond }
+
+// Follow returns the auto-follow time and whether the cue auto-follows.
+func (c Cue) Follow() (time.Duration, bool) {
+	if c.FollowMs == nil {
+		return 0, false
+	}
+	return time.Duration(*c.FollowMs) * time.Millisecond, true
+}
+
+// Duration returns the time from GO until the cue's fades complete.
+func (c Cue) Duration() time.Duration {
+	return c.Delay() + max(c.FadeIn(), c.FadeOut())
+}
+
+// MergeRule returns the merge rule for cue playback, defaulting to HTP.
+func (c CueConfig) MergeRule() string {
+	if c.Merge == "" {
+		return MergeHTP
+	}
+	return c.Merge
+}
+
+// ValidateCues checks the cue merge rule and the timings of every cue.
+// Scenes are checked when a cue is taken, since they are stored separately.
+func ValidateCues(c CueConfig) error {
+	if rule := c.MergeRule(); rule != MergeHTP && rule != MergeLTP {
+		return fmt.Errorf("cues: unknown merge %q (want htp or ltp)", c.Merge)
+	}
+	for name, cues := range c.Lists {
+		if name == "" {
+			return fmt.Errorf("cues: list name must not be empty")
+		}
+		for i, cue := range cues {
+			if cue.Scene == "" {
+				return fmt.Errorf("cue list %q: cue %d has no scene", name, i+1)
+			}
+			if cue.FadeInMs < 0 || cue.FadeOutMs < 0 || cue.DelayMs < 0 || (cue.FollowMs != nil && *cue.FollowMs < 0) {
+				return fmt.Errorf("cue list %q: cue %d: times must not be negative", name, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// DefaultPriority is the E1.31 priority used when a universe doesn't set one.
+const DefaultPriority = 100
+
+// EmitterConfig holds timeout thresholds for emitter connection state
+// detection, and what the rig shows once the emitter goes quiet.
+// Several emitters can send at once; Merge resolves a parameter that more
+// than one of them drives: "htp" (the default) takes the highest value,
+// "ltp" the most recently changed and "priority" the value from the
+// highest-priority emitter. Sources configures emitters by source address.
+// StaleMs is how far behind the emitter's clock a packet may arrive before
+// it is dropped as stale.
 type EmitterConfig struct {
-	IdleTimeoutSec       int `json:"idle_timeout_s"`
-	DisconnectTimeoutSec int `json:"disconnect_timeout_s"`
+	IdleTimeoutSec       int                      `json:"idle_timeout_s"`
+	DisconnectTimeoutSec int                      `json:"disconnect_timeout_s"`
+	StaleMs              int                      `json:"stale_ms"`
+	Failsafe             FailsafeConfig           `json:"failsafe"`
+	Merge                string                   `json:"merge,omitempty"`
+	Sources              map[string]EmitterSource `json:"sources,omitempty"`
+}
+
+// EmitterSource configures the emitter at one source address, keyed by
+// "ip:port" or by "ip" for every port. Namespace, if set, prefixes each of
+// its parameters as "<namespace>.<parameter>". Priority is used by the
+// "priority" merge rule and defaults to DefaultPriority.
+type EmitterSource struct {
+	Label     string `json:"label,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Priority  int    `json:"priority,omitempty"`
+}
+
+// Source returns the settings for the emitter at addr ("ip:port"): those
+// for the exact address, else those for its IP, else none.
+func (e EmitterConfig) Source(addr string) EmitterSource {
+	if s, ok := e.Sources[addr]; ok {
+		return s
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return e.Sources[host]
+	}
+	return EmitterSource{}
+}
+
+// EmitterPriority returns s.Priority, defaulting to DefaultPriority.
+func (s EmitterSource) EmitterPriority() int {
+	if s.Priority == 0 {
+		return DefaultPriority
+	}
+	return s.Priority
+}
+
+// MergeRule returns the emitter merge rule, defaulting to HTP.
+func (e EmitterConfig) MergeRule() string {
+	if e.Merge == "" {
+		return MergeHTP
+	}
+	return e.Merge
+}
+
+// State returns the connection state of an emitter last heard from elapsed
+// ago.
+func (e EmitterConfig) State(elapsed time.Duration) EmitterState {
+	if elapsed >= time.Duration(e.DisconnectTimeoutSec)*time.Second {
+		return EmitterDisconnected
+	}
+	if elapsed >= time.Duration(e.IdleTimeoutSec)*time.Second {
+		return EmitterIdle
+	}
+	return EmitterConnected
+}
+
+// ValidateEmitter checks the merge rule, the emitter sources and the
+// failsafe.
+func ValidateEmitter(e EmitterConfig) error {
+	if !slices.Contains([]string{MergeHTP, MergeLTP, MergePriority}, e.MergeRule()) {
+		return fmt.Errorf("emitter: unknown merge %q (want htp, ltp or priority)", e.Merge)
+	}
+	for addr, s := range e.Sources {
+		if net.ParseIP(addr) == nil {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				return fmt.Errorf("emitter: source %q is not an IP or ip:port", addr)
+			}
+		}
+		if strings.Contains(s.Namespace, ".") {
+			return fmt.Errorf("emitter: source %q: namespace %q must not contain '.'", addr, s.Namespace)
+		}
+		if s.Priority < 0 || s.Priority > 200 {
+			return fmt.Errorf("emitter: source %q: priority must be 0-200", addr)
+		}
+	}
+	return ValidateFailsafe(e.Failsafe)
+}
+
+// FailsafeConfig sets the look shown while the emitter is lost. After is the
+// emitter state that trips it, "idle" or "disconnected" (the default).
+// Action is "hold" (the default: keep the last look), "blackout" (as the
+// blackout command, see Config.BlackoutScene) or "scene" (the stored scene
+// named Scene). The failsafe look fades in over FadeMs, and back out to the
+// live look over RestoreMs once packets resume.
+type FailsafeConfig struct {
+	Action    string `json:"action"`
+	After     string `json:"after"`
+	Scene     string `json:"scene,omitempty"`
+	FadeMs    int    `json:"fade_ms"`
+	RestoreMs int    `json:"restore_ms"`
+}
+
+// Failsafe actions for FailsafeConfig.Action.
+const (
+	FailsafeHold     = "hold"
+	FailsafeBlackout = "blackout"
+	FailsafeScene    = "scene"
+)
+
+// Trigger returns the emitter state at or below which the failsafe trips.
+func (f FailsafeConfig) Trigger() EmitterState {
+	if f.After == EmitterIdle.String() {
+		return EmitterIdle
+	}
+	return EmitterDisconnected
+}
+
+// Fade returns the time to fade into the failsafe look.
+func (f FailsafeConfig) Fade() time.Duration { return time.Duration(f.FadeMs) * time.Millisecond }
+
+// Restore returns the time to fade back to the live look.
+func (f FailsafeConfig) Restore() time.Duration { return time.Duration(f.RestoreMs) * time.Millisecond }
+
+// ValidateFailsafe checks the failsafe action, trigger and timings.
+func ValidateFailsafe(f FailsafeConfig) error {
+	if !slices.Contains([]string{FailsafeHold, FailsafeBlackout, FailsafeScene}, f.Action) {
+		return fmt.Errorf("emitter.failsafe: unknown action %q (want hold, blackout or scene)", f.Action)
+	}
+	if f.After != EmitterIdle.String() && f.After != EmitterDisconnected.String() {
+		return fmt.Errorf("emitter.failsafe: unknown after %q (want idle or disconnected)", f.After)
+	}
+	if f.Action == FailsafeScene && f.Scene == "" {
+		return fmt.Errorf("emitter.failsafe: action scene needs a scene name")
+	}
+	if f.FadeMs < 0 || f.RestoreMs < 0 {
+		return fmt.Errorf("emitter.failsafe: fade_ms and restore_ms must not be negative")
+	}
+	return nil
 }
 
 // EmitterState represents the tri-state emitter connection status.
@@ -47,27 +406,389 @@ func (s EmitterState) String() string {
 // within a universe. Library fixtures reference a key in the fixture store;
 // manual fixtures carry their own channel names.
 type Patch struct {
-	FixtureKey   string   `json:"fixtureKey"`
-	Label        string   `json:"label"`
-	StartAddress int      `json:"startAddress"`
-	Channels     []string `json:"channels,omitempty"` // only for fixtureKey == "manual"
+	FixtureKey   string         `json:"fixtureKey"`
+	Label        string         `json:"label"`
+	StartAddress int            `json:"startAddress"`
+	Channels     []string       `json:"channels,omitempty"` // only for fixtureKey == "manual"
+	Defaults     map[string]int `json:"defaults,omitempty"` // channel name -> DMX value, overrides the fixture's defaults
 }
 
 // UniverseConfig maps a universe number (integer key) to its WLED device IP and label.
-// DeviceIP is the unicast LAN address used for HTTP health probing — not the E1.31
-// multicast destination, which is derived from the universe number directly.
-// Type is "wled" or "gateway" — the WLED prober only probes "wled" devices.
+// DeviceIP is the unicast LAN address used for HTTP health probing, and the
+// E1.31 unicast destination when Delivery is "unicast" or "both". The multicast
+// destination is derived from the universe number directly.
+// Type is "wled", "gateway" or "usb" — the WLED prober only probes "wled" devices.
+// Protocol selects the output transport: "e131" (default), "artnet" for
+// gateways, "ddp" for WLED devices, or "enttec-pro" / "open-dmx" for a USB-DMX
+// dongle on the serial device in Serial. Art-Net has no multicast, so
+// "multicast" delivery broadcasts; DDP is always unicast to DeviceIP.
+// Sync opts the universe into E1.31 synchronization on E131Config.SyncUniverse;
+// leave it off for devices that don't implement it, or they will never update.
+// Merge enables sACN input: sources heard on InputUniverse (default: the same
+// universe number) are merged with Penumbra's own output using the given
+// policy before the universe is sent.
 type UniverseConfig struct {
-	DeviceIP string  `json:"device_ip"`
-	Type     string  `json:"type"`
-	Label    string  `json:"label"`
-	Patches  []Patch `json:"patches,omitempty"`
+	DeviceIP      string   `json:"device_ip"`
+	Type          string   `json:"type"`
+	Label         string   `json:"label"`
+	Delivery      string   `json:"delivery,omitempty"`     // "multicast" (default), "unicast" or "both"
+	Destinations  []string `json:"destinations,omitempty"` // extra unicast IPs, sent in any delivery mode
+	Protocol      string   `json:"protocol,omitempty"`     // "e131" (default), "artnet", "ddp", "enttec-pro" or "open-dmx"
+	Priority      int      `json:"priority,omitempty"`     // E1.31 priority 1–200; 0 means DefaultPriority
+	Sync          bool     `json:"sync,omitempty"`
+	ArtNet        *ArtNet  `json:"artnet,omitempty"`         // Art-Net addressing; nil derives it from the universe number
+	Serial        *Serial  `json:"serial,omitempty"`         // USB-DMX device for "enttec-pro" and "open-dmx"
+	Merge         string   `json:"merge,omitempty"`          // sACN input merge: "" (off), "htp", "ltp" or "priority"
+	InputUniverse int      `json:"input_universe,omitempty"` // sACN universe to merge; 0 means the same number
+	Patches       []Patch  `json:"patches,omitempty"`
+}
+
+// Output protocols for UniverseConfig.Protocol.
+const (
+	ProtocolE131      = "e131"
+	ProtocolArtNet    = "artnet"
+	ProtocolDDP       = "ddp"
+	ProtocolEnttecPro = "enttec-pro"
+	ProtocolOpenDMX   = "open-dmx"
+)
+
+// OutputProtocol returns u.Protocol, defaulting to E1.31.
+func (u UniverseConfig) OutputProtocol() string {
+	if u.Protocol == "" {
+		return ProtocolE131
+	}
+	return u.Protocol
+}
+
+// ArtNet is the Art-Net 4 address of a gateway universe.
+// Broadcast is the address used for broadcast delivery (default 255.255.255.255).
+type ArtNet struct {
+	Net       int    `json:"net"`      // 0–127
+	SubNet    int    `json:"subnet"`   // 0–15
+	Universe  int    `json:"universe"` // 0–15
+	Broadcast string `json:"broadcast,omitempty"`
+}
+
+// ArtNetAddress returns the universe's Art-Net address. Without an explicit
+// "artnet" block, universe N maps to port-address N-1 (sACN 1 = Art-Net 0:0:0).
+func (u UniverseConfig) ArtNetAddress(id int) ArtNet {
+	if u.ArtNet != nil {
+		a := *u.ArtNet
+		if a.Broadcast == "" {
+			a.Broadcast = "255.255.255.255"
+		}
+		return a
+	}
+	port := (id - 1) & 0x7fff
+	return ArtNet{
+		Net:       port >> 8,
+		SubNet:    (port >> 4) & 0x0f,
+		Universe:  port & 0x0f,
+		Broadcast: "255.255.255.255",
+	}
+}
+
+// PortAddress returns the 15-bit Art-Net Port-Address (Net:SubNet:Universe).
+func (a ArtNet) PortAddress() uint16 {
+	return uint16(a.Net&0x7f)<<8 | uint16(a.SubNet&0x0f)<<4 | uint16(a.Universe&0x0f)
+}
+
+// Serial is the USB-DMX dongle a "usb" universe is bound to.
+type Serial struct {
+	Device string `json:"device"` // e.g. "/dev/ttyUSB0"
+}
+
+// Merge policies for UniverseConfig.Merge.
+//
+//   - MergeHTP: highest value per channel wins, across every source.
+//   - MergeLTP: the most recently changed value per channel wins.
+//   - MergePriority: only the sources with the highest E1.31 priority
+//     (Penumbra's own is E131Priority) count; ties merge HTP.
+const (
+	MergeHTP      = "htp"
+	MergeLTP      = "ltp"
+	MergePriority = "priority"
+)
+
+// SACNInput returns the sACN universe merged into universe id, or 0 if the
+// universe has no sACN input.
+func (u UniverseConfig) SACNInput(id int) int {
+	if u.Merge == "" {
+		return 0
+	}
+	if u.InputUniverse != 0 {
+		return u.InputUniverse
+	}
+	return id
+}
+
+// E131Priority returns u.Priority, defaulting to DefaultPriority.
+func (u UniverseConfig) E131Priority() uint8 {
+	if u.Priority == 0 {
+		return DefaultPriority
+	}
+	return uint8(u.Priority)
+}
+
+// Delivery modes for UniverseConfig.Delivery.
+const (
+	DeliveryMulticast = "multicast"
+	DeliveryUnicast   = "unicast"
+	DeliveryBoth      = "both"
+)
+
+// DeliveryMode returns u.Delivery, defaulting to multicast.
+func (u UniverseConfig) DeliveryMode() string {
+	if u.Delivery == "" {
+		return DeliveryMulticast
+	}
+	return u.Delivery
+}
+
+// Multicast reports whether the universe is sent to its multicast group.
+func (u UniverseConfig) Multicast() bool {
+	mode := u.DeliveryMode()
+	return mode == DeliveryMulticast || mode == DeliveryBoth
+}
+
+// UnicastTargets returns the unicast IPs the universe is sent to:
+// DeviceIP for "unicast"/"both" delivery, followed by any extra Destinations.
+// Duplicates are dropped.
+func (u UniverseConfig) UnicastTargets() []string {
+	var targets []string
+	seen := make(map[string]bool)
+	add := func(ip string) {
+		if ip != "" && !seen[ip] {
+			seen[ip] = true
+			targets = append(targets, ip)
+		}
+	}
+	if mode := u.DeliveryMode(); mode == DeliveryUnicast || mode == DeliveryBoth {
+		add(u.DeviceIP)
+	}
+	for _, d := range u.Destinations {
+		add(d)
+	}
+	return targets
+}
+
+// ValidateUniverses checks every universe in the config: its output settings
+// (ValidateUniverse), its patches and their targets (ValidatePatches) and its
+// patch defaults (ValidatePatchDefaults).
+func (c *Config) ValidateUniverses(count ChannelCountResolver, resolve FixtureResolver) error {
+	ids := make([]int, 0, len(c.Universes))
+	for uid := range c.Universes {
+		ids = append(ids, uid)
+	}
+	sort.Ints(ids)
+	for _, uid := range ids {
+		u := c.Universes[uid]
+		if err := ValidateUniverse(u); err != nil {
+			return fmt.Errorf("universe %d: %w", uid, err)
+		}
+		if err := ValidatePatches(u.Patches, c.UniverseTargets(uid), count); err != nil {
+			return fmt.Errorf("universe %d: %w", uid, err)
+		}
+		if err := ValidatePatchDefaults(u.Patches, resolve); err != nil {
+			return fmt.Errorf("universe %d: %w", uid, err)
+		}
+	}
+	return nil
+}
+
+// ValidateSync checks E1.31 synchronization: SyncUniverse must be 0 or a
+// universe number that no E1.31 universe sends data on, and universes can
+// only opt in with Sync when it is set.
+func (c *Config) ValidateSync() error {
+	sync := c.E131.SyncUniverse
+	if sync != 0 {
+		if sync < 1 || sync > 63999 {
+			return fmt.Errorf("e131 sync_universe %d out of range 1-63999", sync)
+		}
+		if u, ok := c.Universes[sync]; ok && u.OutputProtocol() == ProtocolE131 {
+			return fmt.Errorf("e131 sync_universe %d is also a data universe", sync)
+		}
+		return nil
+	}
+	ids := make([]int, 0, len(c.Universes))
+	for uid := range c.Universes {
+		ids = append(ids, uid)
+	}
+	sort.Ints(ids)
+	for _, uid := range ids {
+		if c.Universes[uid].Sync {
+			return fmt.Errorf("universe %d: sync requires e131 sync_universe", uid)
+		}
+	}
+	return nil
+}
+
+// ValidateUniverse checks a universe's output settings: protocol, Art-Net
+// addressing, serial device, delivery mode and E1.31 priority.
+func ValidateUniverse(u UniverseConfig) error {
+	switch u.OutputProtocol() {
+	case ProtocolE131:
+	case ProtocolArtNet:
+		if u.Type != "gateway" {
+			return fmt.Errorf("protocol %q is only supported on gateway universes", u.Protocol)
+		}
+		if a := u.ArtNet; a != nil {
+			if a.Net < 0 || a.Net > 127 || a.SubNet < 0 || a.SubNet > 15 || a.Universe < 0 || a.Universe > 15 {
+				return fmt.Errorf("artnet address %d:%d:%d out of range (net 0-127, subnet 0-15, universe 0-15)",
+					a.Net, a.SubNet, a.Universe)
+			}
+			if a.Broadcast != "" && net.ParseIP(a.Broadcast) == nil {
+				return fmt.Errorf("artnet broadcast %q is not an IP address", a.Broadcast)
+			}
+		}
+	case ProtocolDDP:
+		if u.Type != "wled" {
+			return fmt.Errorf("protocol %q is only supported on wled universes", u.Protocol)
+		}
+		if u.DeviceIP == "" {
+			return fmt.Errorf("protocol %q requires device_ip", u.Protocol)
+		}
+	case ProtocolEnttecPro, ProtocolOpenDMX:
+		if u.Type != "usb" {
+			return fmt.Errorf("protocol %q is only supported on usb universes", u.Protocol)
+		}
+		if u.Serial == nil || u.Serial.Device == "" {
+			return fmt.Errorf("protocol %q requires serial.device", u.Protocol)
+		}
+		return ValidateMerge(u)
+	default:
+		return fmt.Errorf("unknown protocol %q (want e131, artnet, ddp, enttec-pro or open-dmx)", u.Protocol)
+	}
+	if u.Type == "usb" {
+		return fmt.Errorf("usb universes need protocol enttec-pro or open-dmx")
+	}
+	if u.Priority < 0 || u.Priority > 200 {
+		return fmt.Errorf("priority %d out of range 1-200", u.Priority)
+	}
+	if err := ValidateMerge(u); err != nil {
+		return err
+	}
+	return ValidateDelivery(u)
+}
+
+// ValidateMerge checks the sACN input merge policy and universe.
+func ValidateMerge(u UniverseConfig) error {
+	switch u.Merge {
+	case "", MergeHTP, MergeLTP, MergePriority:
+	default:
+		return fmt.Errorf("unknown merge %q (want htp, ltp or priority)", u.Merge)
+	}
+	if u.InputUniverse < 0 || u.InputUniverse > 63999 {
+		return fmt.Errorf("input_universe %d out of range 1-63999", u.InputUniverse)
+	}
+	return nil
+}
+
+// ValidateDelivery checks that the delivery mode is known and that unicast
+// delivery has an address to send to.
+func ValidateDelivery(u UniverseConfig) error {
+	switch u.DeliveryMode() {
+	case DeliveryMulticast:
+	case DeliveryUnicast, DeliveryBoth:
+		if u.DeviceIP == "" {
+			return fmt.Errorf("delivery %q requires device_ip", u.Delivery)
+		}
+	default:
+		return fmt.Errorf("unknown delivery %q (want multicast, unicast or both)", u.Delivery)
+	}
+	for _, d := range u.Destinations {
+		if net.ParseIP(d) == nil {
+			return fmt.Errorf("destination %q is not an IP address", d)
+		}
+	}
+	return nil
 }
 
 // ChannelTarget identifies a single DMX channel within a universe.
+// A 16-bit target (Width 16) splits the value across a coarse channel
+// (Channel) and a fine channel, by default the next one; FineChannel
+// overrides that for fixtures that don't place them side by side.
+//
+// The parameter value is transformed before it is scaled to DMX: first the
+// response Curve, then Invert, then the result is mapped into Min–Max (both
+// normalised 0–1). A zero Max means 1, so the default is the full range.
 type ChannelTarget struct {
-	Universe int `json:"universe"`
-	Channel  int `json:"channel"` // 1-indexed DMX channel
+	Universe    int       `json:"universe"`
+	Channel     int       `json:"channel"`                // 1-indexed DMX channel
+	Width       int       `json:"width,omitempty"`        // 8 (default) or 16
+	FineChannel int       `json:"fine_channel,omitempty"` // 16-bit only; 0 means Channel+1
+	Min         float64   `json:"min,omitempty"`
+	Max         float64   `json:"max,omitempty"`
+	Invert      bool      `json:"invert,omitempty"`
+	Curve       string    `json:"curve,omitempty"` // "linear" (default), "square", "gamma", "scurve" or "lut"
+	Gamma       float64   `json:"gamma,omitempty"` // "gamma" exponent; 0 means DefaultGamma
+	LUT         []float64 `json:"lut,omitempty"`   // "lut" outputs at evenly spaced inputs 0–1, interpolated linearly
+}
+
+// Response curves for ChannelTarget.Curve.
+const (
+	CurveLinear = "linear"
+	CurveSquare = "square"
+	CurveGamma  = "gamma"
+	CurveSCurve = "scurve"
+	CurveLUT    = "lut"
+)
+
+// DefaultGamma is the exponent used by the "gamma" curve when Gamma is 0.
+const DefaultGamma = 2.2
+
+// OutputRange returns the target's Min and Max, defaulting Max to 1.
+func (t ChannelTarget) OutputRange() (lo, hi float64) {
+	if t.Max == 0 {
+		return t.Min, 1
+	}
+	return t.Min, t.Max
+}
+
+// ValidateTarget checks a target's width and value transform.
+func ValidateTarget(t ChannelTarget) error {
+	if t.Width != 0 && t.Width != 8 && t.Width != 16 {
+		return fmt.Errorf("width %d (want 8 or 16)", t.Width)
+	}
+	lo, hi := t.OutputRange()
+	if lo < 0 || lo > 1 || hi < 0 || hi > 1 || lo > hi {
+		return fmt.Errorf("range %g-%g must be within 0-1 with min <= max (use invert to reverse)", lo, hi)
+	}
+	switch t.Curve {
+	case "", CurveLinear, CurveSquare, CurveSCurve:
+	case CurveGamma:
+		if t.Gamma < 0 {
+			return fmt.Errorf("gamma %g must be positive", t.Gamma)
+		}
+	case CurveLUT:
+		if len(t.LUT) < 2 {
+			return fmt.Errorf("curve \"lut\" needs at least 2 lut entries")
+		}
+		for _, v := range t.LUT {
+			if v < 0 || v > 1 {
+				return fmt.Errorf("lut value %g out of range 0-1", v)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown curve %q (want linear, square, gamma, scurve or lut)", t.Curve)
+	}
+	return nil
+}
+
+// Is16Bit reports whether t spans a coarse and a fine channel.
+func (t ChannelTarget) Is16Bit() bool {
+	return t.Width == 16
+}
+
+// Fine returns the fine channel of a 16-bit target, or 0 for an 8-bit one.
+func (t ChannelTarget) Fine() int {
+	if !t.Is16Bit() {
+		return 0
+	}
+	if t.FineChannel != 0 {
+		return t.FineChannel
+	}
+	return t.Channel + 1
 }
 
 // ParameterConfig is the list of DMX targets driven by a single parameter.
@@ -105,6 +826,48 @@ func (c *Config) applyDefaults() {
 	if c.Emitter.DisconnectTimeoutSec <= 0 {
 		c.Emitter.DisconnectTimeoutSec = 3600
 	}
+	if c.Emitter.StaleMs <= 0 {
+		c.Emitter.StaleMs = 1000
+	}
+	if c.Emitter.Failsafe.Action == "" {
+		c.Emitter.Failsafe.Action = FailsafeHold
+	}
+	if c.Emitter.Failsafe.After == "" {
+		c.Emitter.Failsafe.After = EmitterDisconnected.String()
+	}
+	if len(c.Output.Drivers) == 0 {
+		c.Output.Drivers = []string{"e131", "artnet", "ddp", "enttec-pro", "open-dmx"}
+	}
+	if c.Output.RefreshHz <= 0 {
+		c.Output.RefreshHz = 44
+	}
+	if c.Output.KeepAliveMs <= 0 {
+		c.Output.KeepAliveMs = 1000
+	}
+	if c.E131.SourceName == "" {
+		c.E131.SourceName = "penumbra"
+	}
+	if c.Fades.BlackoutMs < 0 {
+		c.Fades.BlackoutMs = 0
+	}
+	if c.Fades.ResetMs < 0 {
+		c.Fades.ResetMs = 0
+	}
+	if c.Fades.SessionMs < 0 {
+		c.Fades.SessionMs = 0
+	}
+	if c.Smoothing.Mode == "" {
+		c.Smoothing.Mode = SmoothingNone
+	}
+	if c.Smoothing.TimeConstantMs == 0 {
+		c.Smoothing.TimeConstantMs = 50
+	}
+}
+
+// Dir returns the directory config.json lives in. Files that belong with the
+// config (e.g. the persisted E1.31 CID) are stored here.
+func (c *Config) Dir() string {
+	return filepath.Dir(c.path)
 }
 
 func cwd() string {
@@ -118,15 +881,288 @@ func cwd() string {
 // ChannelCountResolver returns the channel count for a fixture key.
 type ChannelCountResolver func(key string) int
 
-// ValidatePatches checks that no two patches in a universe overlap
-// and that all patches fit within the 512-channel DMX range.
-func ValidatePatches(patches []Patch, resolve ChannelCountResolver) error {
-	occupied := make(map[int]string) // channel -> patch label
+// ChannelCount returns how many DMX channels the patch occupies: the manual
+// channel list, or the library fixture's channel count via resolve.
+func (p Patch) ChannelCount(resolve ChannelCountResolver) int {
+	if p.FixtureKey == "manual" {
+		return len(p.Channels)
+	}
+	return resolve(p.FixtureKey)
+}
+
+// UniverseTargets returns the targets in universe, keyed by parameter name.
+func (c *Config) UniverseTargets(universe int) map[string][]ChannelTarget {
+	targets := make(map[string][]ChannelTarget)
+	for param, pc := range c.Parameters {
+		for _, t := range pc {
+			if t.Universe == universe {
+				targets[param] = append(targets[param], t)
+			}
+		}
+	}
+	return targets
+}
+
+// FixtureResolver returns a fixture's channel names and default values
+// (channel name -> DMX value) for a fixture key.
+type FixtureResolver func(key string) (channels []string, defaults map[string]int)
+
+// ChannelNames returns the patch's channel names in address order: the
+// manual channel list, or the library fixture's channels via resolve.
+func (p Patch) ChannelNames(resolve FixtureResolver) []string {
+	if p.FixtureKey == "manual" {
+		return p.Channels
+	}
+	channels, _ := resolve(p.FixtureKey)
+	return channels
+}
+
+// ChannelDefaults returns the value each channel of the patch holds when no
+// parameter drives it, keyed by 1-indexed DMX address: the fixture's defaults
+// overridden by the patch's own. Channels without a default are omitted.
+func (p Patch) ChannelDefaults(resolve FixtureResolver) map[int]byte {
+	channels, defaults := p.Channels, map[string]int(nil)
+	if p.FixtureKey != "manual" {
+		channels, defaults = resolve(p.FixtureKey)
+	}
+	out := make(map[int]byte)
+	for i, name := range channels {
+		v, ok := p.Defaults[name]
+		if !ok {
+			v, ok = defaults[name]
+		}
+		if ok {
+			out[p.StartAddress+i] = byte(v)
+		}
+	}
+	return out
+}
+
+// Submaster is a named group scaled by one sub-master level. Patches are
+// matched by label and contribute their intensity channels (see
+// Patch.IntensityChannels); Parameters contribute every channel they target.
+type Submaster struct {
+	Patches    []string `json:"patches,omitempty"`
+	Parameters []string `json:"parameters,omitempty"`
+}
+
+// ValidateSubmasters checks that every sub-master member exists.
+func (c *Config) ValidateSubmasters() error {
+	labels := make(map[string]bool)
+	for _, u := range c.Universes {
+		for _, p := range u.Patches {
+			labels[p.Label] = true
+		}
+	}
+	for name, sm := range c.Submasters {
+		if name == "" {
+			return fmt.Errorf("submaster: name must not be empty")
+		}
+		for _, label := range sm.Patches {
+			if !labels[label] {
+				return fmt.Errorf("submaster %q: no patch labelled %q", name, label)
+			}
+		}
+		for _, param := range sm.Parameters {
+			if _, ok := c.Parameters[param]; !ok {
+				return fmt.Errorf("submaster %q: unknown parameter %q", name, param)
+			}
+		}
+	}
+	return nil
+}
+
+// Intensity is a channel that masters scale: Channel is the 1-indexed DMX
+// address and Fine the address of its fine byte, or 0 for an 8-bit channel.
+type Intensity struct {
+	Channel int
+	Fine    int
+}
+
+var (
+	dimmerNames = []string{"dimmer", "intensity", "master"}
+	colourNames = []string{"red", "green", "blue", "white", "warm white", "cool white", "amber", "uv", "lime", "cyan", "magenta", "yellow"}
+)
+
+// IsDimmer reports whether a fixture channel name is a dimmer channel.
+func IsDimmer(name string) bool {
+	return slices.Contains(dimmerNames, strings.ToLower(name))
+}
+
+// IsColour reports whether a fixture channel name is a colour emitter.
+func IsColour(name string) bool {
+	return slices.Contains(colourNames, strings.ToLower(name))
+}
+
+// IntensityChannels returns the channels of the patch that control its light
+// output: its dimmer channels if it has any (with their "<name> Fine"
+// channel, if present), otherwise its colour emitters. Scaling only the
+// dimmer keeps a dimmer-and-colour fixture's colour while it dims.
+func (p Patch) IntensityChannels(resolve FixtureResolver) []Intensity {
+	channels := p.ChannelNames(resolve)
+	find := func(name string) int {
+		for i, ch := range channels {
+			if strings.EqualFold(ch, name) {
+				return p.StartAddress + i
+			}
+		}
+		return 0
+	}
+	var out []Intensity
+	for i, name := range channels {
+		if IsDimmer(name) {
+			out = append(out, Intensity{Channel: p.StartAddress + i, Fine: find(name + " Fine")})
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+	for i, name := range channels {
+		if IsColour(name) {
+			out = append(out, Intensity{Channel: p.StartAddress + i})
+		}
+	}
+	return out
+}
+
+// ColorParam is a virtual colour parameter: emitter parameters in a colour
+// model, converted at output to the colour channels of one patch.
+// Inputs maps each component of Model to the emitter parameter that drives
+// it; components without an input take a default:
+//
+//	"hsv": "hue" (0), "saturation" (1), "value" (1)
+//	"rgb": "red", "green", "blue" (0), "value" (1)
+//	"cct": "kelvin" (0), "value" (1)
+//
+// All inputs are normalised 0–1; "kelvin" spans MinKelvin–MaxKelvin.
+type ColorParam struct {
+	Universe  int               `json:"universe"`
+	Patch     string            `json:"patch"` // label of a patch in Universe
+	Model     string            `json:"model"`
+	Inputs    map[string]string `json:"inputs"`
+	MinKelvin int               `json:"min_kelvin,omitempty"` // default 2700
+	MaxKelvin int               `json:"max_kelvin,omitempty"` // default 6500
+}
+
+// Colour models for ColorParam.
+const (
+	ColorHSV = "hsv"
+	ColorRGB = "rgb"
+	ColorCCT = "cct"
+)
+
+// colorParamChannels are the channels a ColorParam drives, besides the
+// patch's dimmer. Other colour emitters (UV, lime, ...) keep their own
+// parameters.
+var colorParamChannels = []string{"red", "green", "blue", "white", "warm white", "cool white", "amber"}
+
+// colorComponents lists the components each colour model accepts.
+var colorComponents = map[string][]string{
+	ColorHSV: {"hue", "saturation", "value"},
+	ColorRGB: {"red", "green", "blue", "value"},
+	ColorCCT: {"kelvin", "value"},
+}
+
+// KelvinRange returns the colour temperatures a "kelvin" input of 0 and 1
+// map to.
+func (c ColorParam) KelvinRange() (lo, hi float64) {
+	lo, hi = 2700, 6500
+	if c.MinKelvin > 0 {
+		lo = float64(c.MinKelvin)
+	}
+	if c.MaxKelvin > 0 {
+		hi = float64(c.MaxKelvin)
+	}
+	return lo, hi
+}
+
+// ColorPatch returns the patch a colour parameter drives.
+func (c *Config) ColorPatch(cp ColorParam) (Patch, bool) {
+	for _, p := range c.Universes[cp.Universe].Patches {
+		if p.Label == cp.Patch {
+			return p, true
+		}
+	}
+	return Patch{}, false
+}
+
+// ValidateColors checks every colour parameter: its model and inputs, that
+// its patch exists and has colour channels, and that no parameter target
+// maps one of the channels it drives.
+func (c *Config) ValidateColors(resolve FixtureResolver) error {
+	for name, cp := range c.Colors {
+		components, ok := colorComponents[cp.Model]
+		if !ok {
+			return fmt.Errorf("color %q: unknown model %q (want hsv, rgb or cct)", name, cp.Model)
+		}
+		for component := range cp.Inputs {
+			if !slices.Contains(components, component) {
+				return fmt.Errorf("color %q: model %s has no component %q", name, cp.Model, component)
+			}
+		}
+		if lo, hi := cp.KelvinRange(); lo >= hi {
+			return fmt.Errorf("color %q: min_kelvin must be below max_kelvin", name)
+		}
+		p, ok := c.ColorPatch(cp)
+		if !ok {
+			return fmt.Errorf("color %q: no patch labelled %q in universe %d", name, cp.Patch, cp.Universe)
+		}
+		driven := make(map[int]bool)
+		channels := p.ChannelNames(resolve)
+		for i, ch := range channels {
+			if slices.Contains(colorParamChannels, strings.ToLower(ch)) {
+				driven[p.StartAddress+i] = true
+			}
+		}
+		if len(driven) == 0 {
+			return fmt.Errorf("color %q: fixture %q has no colour channels", name, p.Label)
+		}
+		for i, ch := range channels {
+			if IsDimmer(ch) || (strings.HasSuffix(ch, " Fine") && IsDimmer(strings.TrimSuffix(ch, " Fine"))) {
+				driven[p.StartAddress+i] = true
+			}
+		}
+		for param, targets := range c.UniverseTargets(cp.Universe) {
+			for _, t := range targets {
+				if driven[t.Channel] || (t.Is16Bit() && driven[t.Fine()]) {
+					return fmt.Errorf("color %q: channel %d of %q is also mapped by parameter %q", name, t.Channel, p.Label, param)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePatchDefaults checks that every patch default names one of the
+// fixture's channels and is a DMX value.
+func ValidatePatchDefaults(patches []Patch, resolve FixtureResolver) error {
 	for _, p := range patches {
-		count := len(p.Channels)
+		channels := p.Channels
 		if p.FixtureKey != "manual" {
-			count = resolve(p.FixtureKey)
+			channels, _ = resolve(p.FixtureKey)
 		}
+		for name, v := range p.Defaults {
+			if !slices.Contains(channels, name) {
+				return fmt.Errorf("fixture %q has no channel %q", p.Label, name)
+			}
+			if v < 0 || v > 255 {
+				return fmt.Errorf("fixture %q: default %d for %q out of range 0-255", p.Label, v, name)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePatches checks that no two patches in a universe overlap, that all
+// patches fit within the 512-channel DMX range, and that the universe's
+// targets (keyed by parameter, see UniverseTargets) are well formed: valid
+// transforms (ValidateTarget) and, for 16-bit targets, both channels in
+// range, the fine channel inside the same patch as the coarse one and not
+// claimed by any other target.
+func ValidatePatches(patches []Patch, targets map[string][]ChannelTarget, resolve ChannelCountResolver) error {
+	occupied := make(map[int]string) // channel -> patch label
+	for _, p := range patches {
+		count := p.ChannelCount(resolve)
 		if count == 0 {
 			return fmt.Errorf("fixture %q has 0 channels", p.Label)
 		}
@@ -141,6 +1177,48 @@ func ValidatePatches(patches []Patch, resolve ChannelCountResolver) error {
 			occupied[ch] = p.Label
 		}
 	}
+	return validateTargets(patches, targets, resolve)
+}
+
+func validateTargets(patches []Patch, targets map[string][]ChannelTarget, resolve ChannelCountResolver) error {
+	params := make([]string, 0, len(targets))
+	for param := range targets {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	mapped := make(map[int]string) // coarse and 8-bit channels -> parameter
+	for _, param := range params {
+		for _, t := range targets[param] {
+			mapped[t.Channel] = param
+		}
+	}
+	for _, param := range params {
+		for _, t := range targets[param] {
+			if err := ValidateTarget(t); err != nil {
+				return fmt.Errorf("parameter %q: %w", param, err)
+			}
+			if !t.Is16Bit() {
+				continue
+			}
+			fine := t.Fine()
+			if t.Channel < 1 || t.Channel > 512 || fine < 1 || fine > 512 || fine == t.Channel {
+				return fmt.Errorf("parameter %q: 16-bit channels %d/%d out of range 1-512", param, t.Channel, fine)
+			}
+			if owner, ok := mapped[fine]; ok {
+				return fmt.Errorf("channel %d: fine channel of %q is also mapped to %q", fine, param, owner)
+			}
+			for _, p := range patches {
+				start, end := p.StartAddress, p.StartAddress+p.ChannelCount(resolve)-1
+				inCoarse := t.Channel >= start && t.Channel <= end
+				inFine := fine >= start && fine <= end
+				if inCoarse != inFine {
+					return fmt.Errorf("parameter %q: coarse channel %d and fine channel %d must be in the same fixture (%q is %d-%d)",
+						param, t.Channel, fine, p.Label, start, end)
+				}
+			}
+		}
+	}
 	return nil
 }
 
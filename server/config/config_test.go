@@ -23,7 +23,7 @@ func TestValidatePatches_NoOverlap(t *testing.T) {
 		{FixtureKey: "generic/rgbaw-6ch", Label: "Front Par", StartAddress: 1},
 		{FixtureKey: "generic/rgb-3ch", Label: "Back Par", StartAddress: 7},
 	}
-	if err := ValidatePatches(patches, fixtureResolver); err != nil {
+	if err := ValidatePatches(patches, nil, fixtureResolver); err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
 }
@@ -33,7 +33,7 @@ func TestValidatePatches_Overlap(t *testing.T) {
 		{FixtureKey: "generic/rgbaw-6ch", Label: "Front Par", StartAddress: 1},
 		{FixtureKey: "generic/rgb-3ch", Label: "Back Par", StartAddress: 5},
 	}
-	err := ValidatePatches(patches, fixtureResolver)
+	err := ValidatePatches(patches, nil, fixtureResolver)
 	if err == nil {
 		t.Fatal("expected overlap error, got nil")
 	}
@@ -46,16 +46,45 @@ func TestValidatePatches_ManualFixture(t *testing.T) {
 	patches := []Patch{
 		{FixtureKey: "manual", Label: "Custom RGB", StartAddress: 1, Channels: []string{"Red", "Green", "Blue"}},
 	}
-	if err := ValidatePatches(patches, fixtureResolver); err != nil {
+	if err := ValidatePatches(patches, nil, fixtureResolver); err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
 }
 
+func TestValidatePatches_16Bit(t *testing.T) {
+	patches := []Patch{
+		{FixtureKey: "generic/moving-head-8ch", Label: "Mover", StartAddress: 1},
+	}
+	ok := map[string][]ChannelTarget{
+		"pan":  {{Universe: 1, Channel: 1, Width: 16}},
+		"tilt": {{Universe: 1, Channel: 3, Width: 16, FineChannel: 4}},
+	}
+	if err := ValidatePatches(patches, ok, fixtureResolver); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	cases := map[string]map[string][]ChannelTarget{
+		"same fixture": {"pan": {{Universe: 1, Channel: 8, Width: 16}}},
+		"also mapped": {
+			"pan":     {{Universe: 1, Channel: 1, Width: 16}},
+			"panFine": {{Universe: 1, Channel: 2}},
+		},
+		"out of range": {"pan": {{Universe: 1, Channel: 512, Width: 16}}},
+		"want 8 or 16": {"pan": {{Universe: 1, Channel: 1, Width: 12}}},
+	}
+	for want, targets := range cases {
+		err := ValidatePatches(patches, targets, fixtureResolver)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v", want, err)
+		}
+	}
+}
+
 func TestValidatePatches_ExceedsDMXRange(t *testing.T) {
 	patches := []Patch{
 		{FixtureKey: "generic/moving-head-8ch", Label: "Mover", StartAddress: 510},
 	}
-	err := ValidatePatches(patches, fixtureResolver)
+	err := ValidatePatches(patches, nil, fixtureResolver)
 	if err == nil {
 		t.Fatal("expected range error, got nil")
 	}
@@ -63,3 +92,340 @@ func TestValidatePatches_ExceedsDMXRange(t *testing.T) {
 		t.Fatalf("expected DMX range error, got: %v", err)
 	}
 }
+
+func TestValidateDelivery(t *testing.T) {
+	cases := []struct {
+		name    string
+		u       UniverseConfig
+		wantErr string
+	}{
+		{"default multicast", UniverseConfig{}, ""},
+		{"unicast with device", UniverseConfig{DeviceIP: "192.168.1.10", Delivery: "unicast"}, ""},
+		{"unicast without device", UniverseConfig{Delivery: "unicast"}, "requires device_ip"},
+		{"unknown mode", UniverseConfig{Delivery: "broadcast"}, "unknown delivery"},
+		{"bad destination", UniverseConfig{Destinations: []string{"not-an-ip"}}, "not an IP"},
+	}
+	for _, tc := range cases {
+		err := ValidateDelivery(tc.u)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: expected no error, got: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error containing %q, got: %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+func TestUnicastTargets(t *testing.T) {
+	u := UniverseConfig{
+		DeviceIP:     "192.168.1.10",
+		Delivery:     "both",
+		Destinations: []string{"192.168.1.20", "192.168.1.10"},
+	}
+	got := u.UnicastTargets()
+	if len(got) != 2 || got[0] != "192.168.1.10" || got[1] != "192.168.1.20" {
+		t.Fatalf("unexpected targets: %v", got)
+	}
+
+	u.Delivery = "multicast"
+	got = u.UnicastTargets()
+	if len(got) != 2 || got[0] != "192.168.1.20" {
+		t.Fatalf("multicast delivery should only keep extra destinations, got: %v", got)
+	}
+}
+
+func TestArtNetAddress(t *testing.T) {
+	derived := UniverseConfig{}.ArtNetAddress(18)
+	if derived.PortAddress() != 17 || derived.SubNet != 1 || derived.Universe != 1 {
+		t.Fatalf("universe 18 should derive port-address 17 (0:1:1), got %+v", derived)
+	}
+	if derived.Broadcast != "255.255.255.255" {
+		t.Fatalf("expected default broadcast, got %q", derived.Broadcast)
+	}
+
+	explicit := UniverseConfig{ArtNet: &ArtNet{Net: 1, SubNet: 2, Universe: 3}}.ArtNetAddress(1)
+	if explicit.PortAddress() != 0x123 {
+		t.Fatalf("expected port-address 0x123, got %#x", explicit.PortAddress())
+	}
+}
+
+func TestValidateUniverse_Protocol(t *testing.T) {
+	if err := ValidateUniverse(UniverseConfig{Type: "gateway", Protocol: "artnet"}); err != nil {
+		t.Fatalf("artnet gateway should be valid, got: %v", err)
+	}
+	err := ValidateUniverse(UniverseConfig{Type: "wled", Protocol: "artnet"})
+	if err == nil || !strings.Contains(err.Error(), "gateway") {
+		t.Fatalf("expected gateway-only error, got: %v", err)
+	}
+	err = ValidateUniverse(UniverseConfig{Type: "gateway", Protocol: "artnet", ArtNet: &ArtNet{SubNet: 16}})
+	if err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Fatalf("expected range error, got: %v", err)
+	}
+	err = ValidateUniverse(UniverseConfig{Type: "wled", Protocol: "ddp"})
+	if err == nil || !strings.Contains(err.Error(), "device_ip") {
+		t.Fatalf("expected device_ip error, got: %v", err)
+	}
+	err = ValidateUniverse(UniverseConfig{Type: "usb", Protocol: "open-dmx"})
+	if err == nil || !strings.Contains(err.Error(), "serial.device") {
+		t.Fatalf("expected serial.device error, got: %v", err)
+	}
+	err = ValidateUniverse(UniverseConfig{Type: "usb", Serial: &Serial{Device: "/dev/ttyUSB0"}})
+	if err == nil || !strings.Contains(err.Error(), "enttec-pro") {
+		t.Fatalf("expected usb protocol error, got: %v", err)
+	}
+	if err := ValidateUniverse(UniverseConfig{Type: "usb", Protocol: "enttec-pro", Serial: &Serial{Device: "/dev/ttyUSB0"}}); err != nil {
+		t.Fatalf("enttec-pro usb universe should be valid, got: %v", err)
+	}
+	err = ValidateUniverse(UniverseConfig{Type: "wled", Merge: "max"})
+	if err == nil || !strings.Contains(err.Error(), "merge") {
+		t.Fatalf("expected merge error, got: %v", err)
+	}
+}
+
+func TestValidateUniverses(t *testing.T) {
+	cfg := &Config{
+		Universes: map[int]UniverseConfig{
+			1: {Type: "wled", Patches: []Patch{{Label: "par", FixtureKey: "generic/rgb-3ch", StartAddress: 1}}},
+			2: {Type: "wled", Priority: 300},
+		},
+	}
+	resolve := func(string) ([]string, map[string]int) { return nil, nil }
+	err := cfg.ValidateUniverses(fixtureResolver, resolve)
+	if err == nil || !strings.Contains(err.Error(), "universe 2: priority 300") {
+		t.Fatalf("expected universe 2 priority error, got: %v", err)
+	}
+
+	cfg.Universes[2] = UniverseConfig{Type: "wled"}
+	cfg.Parameters = map[string]ParameterConfig{"dim": {{Universe: 1, Channel: 1, Curve: "cubic"}}}
+	err = cfg.ValidateUniverses(fixtureResolver, resolve)
+	if err == nil || !strings.Contains(err.Error(), "universe 1: parameter \"dim\"") {
+		t.Fatalf("expected universe 1 target error, got: %v", err)
+	}
+}
+
+func TestValidateSync(t *testing.T) {
+	cfg := &Config{Universes: map[int]UniverseConfig{
+		1: {Sync: true},
+		2: {Type: "gateway", Protocol: ProtocolArtNet},
+	}}
+	cfg.E131.SyncUniverse = 2
+	if err := cfg.ValidateSync(); err != nil {
+		t.Fatalf("sync on an Art-Net universe number should be valid, got: %v", err)
+	}
+	cases := map[int]string{
+		0:     "universe 1: sync requires e131 sync_universe",
+		1:     "also a data universe",
+		64000: "out of range",
+		-1:    "out of range",
+	}
+	for sync, want := range cases {
+		cfg.E131.SyncUniverse = sync
+		if err := cfg.ValidateSync(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("sync_universe %d: expected error containing %q, got: %v", sync, want, err)
+		}
+	}
+	cfg.E131.SyncUniverse = 0
+	cfg.Universes[1] = UniverseConfig{}
+	if err := cfg.ValidateSync(); err != nil {
+		t.Fatalf("sync disabled without sync universes should be valid, got: %v", err)
+	}
+}
+
+func TestSACNInput(t *testing.T) {
+	if got := (UniverseConfig{}).SACNInput(3); got != 0 {
+		t.Fatalf("no merge: input = %d, want 0", got)
+	}
+	if got := (UniverseConfig{Merge: MergeHTP}).SACNInput(3); got != 3 {
+		t.Fatalf("default input = %d, want 3", got)
+	}
+	if got := (UniverseConfig{Merge: MergeLTP, InputUniverse: 10}).SACNInput(3); got != 10 {
+		t.Fatalf("explicit input = %d, want 10", got)
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	if err := ValidateTarget(ChannelTarget{Channel: 1, Max: 0.7, Invert: true, Curve: CurveGamma}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	cases := map[string]ChannelTarget{
+		"min <= max":    {Min: 0.8, Max: 0.2},
+		"unknown curve": {Curve: "log"},
+		"lut entries":   {Curve: CurveLUT, LUT: []float64{0.5}},
+		"lut value":     {Curve: CurveLUT, LUT: []float64{0, 2}},
+	}
+	for want, target := range cases {
+		if err := ValidateTarget(target); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v", want, err)
+		}
+	}
+}
+
+func TestPatchChannelDefaults(t *testing.T) {
+	resolve := func(key string) ([]string, map[string]int) {
+		return []string{"Red", "Green", "Blue", "Mode"}, map[string]int{"Mode": 20}
+	}
+	p := Patch{FixtureKey: "par", Label: "Par", StartAddress: 10, Defaults: map[string]int{"Green": 5}}
+
+	got := p.ChannelDefaults(resolve)
+	if len(got) != 2 || got[11] != 5 || got[13] != 20 {
+		t.Fatalf("defaults = %v, want map[11:5 13:20]", got)
+	}
+
+	bad := []Patch{{FixtureKey: "par", Label: "Par", StartAddress: 1, Defaults: map[string]int{"Amber": 1}}}
+	if err := ValidatePatchDefaults(bad, resolve); err == nil || !strings.Contains(err.Error(), "Amber") {
+		t.Fatalf("expected unknown channel error, got: %v", err)
+	}
+}
+
+func TestPatchIntensityChannels(t *testing.T) {
+	dimmer := Patch{FixtureKey: "manual", StartAddress: 10, Channels: []string{"Pan", "Dimmer", "Dimmer Fine", "Red"}}
+	if got := dimmer.IntensityChannels(nil); len(got) != 1 || got[0] != (Intensity{Channel: 11, Fine: 12}) {
+		t.Fatalf("dimmer patch: %+v, want [{11 12}]", got)
+	}
+
+	rgb := Patch{FixtureKey: "manual", StartAddress: 1, Channels: []string{"Red", "Green", "Blue", "Strobe"}}
+	if got := rgb.IntensityChannels(nil); len(got) != 3 || got[2] != (Intensity{Channel: 3}) {
+		t.Fatalf("rgb patch: %+v, want channels 1-3", got)
+	}
+}
+
+func TestValidateColors(t *testing.T) {
+	cfg := &Config{
+		Universes: map[int]UniverseConfig{1: {Patches: []Patch{
+			{FixtureKey: "manual", Label: "Par", StartAddress: 1, Channels: []string{"Red", "Green", "Blue", "UV"}},
+			{FixtureKey: "manual", Label: "Fog", StartAddress: 10, Channels: []string{"Output"}},
+		}}},
+		Parameters: map[string]ParameterConfig{"uv": {{Universe: 1, Channel: 4}}},
+		Colors: map[string]ColorParam{
+			"par": {Universe: 1, Patch: "Par", Model: ColorHSV, Inputs: map[string]string{"hue": "h"}},
+		},
+	}
+	if err := cfg.ValidateColors(nil); err != nil {
+		t.Fatalf("valid colors: %v", err)
+	}
+
+	bad := map[string]ColorParam{
+		"unknown model":   {Universe: 1, Patch: "Par", Model: "cmy"},
+		"wrong component": {Universe: 1, Patch: "Par", Model: ColorRGB, Inputs: map[string]string{"hue": "h"}},
+		"missing patch":   {Universe: 1, Patch: "Nope", Model: ColorRGB},
+		"no colour":       {Universe: 1, Patch: "Fog", Model: ColorRGB},
+		"inverted kelvin": {Universe: 1, Patch: "Par", Model: ColorCCT, MinKelvin: 6000, MaxKelvin: 3000},
+	}
+	for name, cp := range bad {
+		cfg.Colors = map[string]ColorParam{name: cp}
+		if err := cfg.ValidateColors(nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	cfg.Colors = map[string]ColorParam{"par": {Universe: 1, Patch: "Par", Model: ColorRGB}}
+	cfg.Parameters["red"] = ParameterConfig{{Universe: 1, Channel: 1}}
+	if err := cfg.ValidateColors(nil); err == nil || !strings.Contains(err.Error(), "also mapped") {
+		t.Errorf("expected channel conflict error, got %v", err)
+	}
+}
+
+func TestValidateCues(t *testing.T) {
+	follow := 0
+	ok := CueConfig{Merge: MergeLTP, Lists: map[string][]Cue{"act1": {{Scene: "preset", FadeInMs: 3000, FollowMs: &follow}}}}
+	if err := ValidateCues(ok); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	negative := -1
+	cases := map[string]CueConfig{
+		"unknown merge": {Merge: "priority"},
+		"no scene":      {Lists: map[string][]Cue{"act1": {{}}}},
+		"negative":      {Lists: map[string][]Cue{"act1": {{Scene: "preset", FollowMs: &negative}}}},
+	}
+	for want, c := range cases {
+		if err := ValidateCues(c); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v", want, err)
+		}
+	}
+}
+
+func TestFailsafeConfig(t *testing.T) {
+	f := FailsafeConfig{Action: FailsafeBlackout, After: "idle", FadeMs: 2000}
+	if err := ValidateFailsafe(f); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if f.Trigger() != EmitterIdle {
+		t.Errorf("Trigger() = %v, want idle", f.Trigger())
+	}
+	if f.After = "disconnected"; f.Trigger() != EmitterDisconnected {
+		t.Errorf("Trigger() = %v, want disconnected", f.Trigger())
+	}
+	cases := map[string]FailsafeConfig{
+		"unknown action":  {Action: "strobe", After: "idle"},
+		"unknown after":   {Action: FailsafeHold, After: "connected"},
+		"needs a scene":   {Action: FailsafeScene, After: "idle"},
+		"must not be neg": {Action: FailsafeHold, After: "idle", RestoreMs: -1},
+	}
+	for want, f := range cases {
+		if err := ValidateFailsafe(f); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v", want, err)
+		}
+	}
+}
+
+func TestValidateEmitter(t *testing.T) {
+	e := EmitterConfig{
+		Merge:    MergePriority,
+		Failsafe: FailsafeConfig{Action: FailsafeHold, After: "idle"},
+		Sources: map[string]EmitterSource{
+			"10.0.0.5":       {Namespace: "left", Priority: 150},
+			"10.0.0.6:41234": {Namespace: "right"},
+		},
+	}
+	if err := ValidateEmitter(e); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s := e.Source("10.0.0.5:5000"); s.Namespace != "left" || s.EmitterPriority() != 150 {
+		t.Errorf("Source by IP = %+v", s)
+	}
+	if s := e.Source("10.0.0.6:41234"); s.Namespace != "right" || s.EmitterPriority() != DefaultPriority {
+		t.Errorf("Source by ip:port = %+v", s)
+	}
+	if s := e.Source("10.0.0.6:5000"); s.Namespace != "" {
+		t.Errorf("Source on another port = %+v, want none", s)
+	}
+
+	cases := map[string]EmitterConfig{
+		"unknown merge":     {Merge: "loudest", Failsafe: e.Failsafe},
+		"not an IP":         {Sources: map[string]EmitterSource{"desk": {}}, Failsafe: e.Failsafe},
+		"must not contain":  {Sources: map[string]EmitterSource{"10.0.0.5": {Namespace: "a.b"}}, Failsafe: e.Failsafe},
+		"priority must be":  {Sources: map[string]EmitterSource{"10.0.0.5": {Priority: 201}}, Failsafe: e.Failsafe},
+		"failsafe: unknown": {Failsafe: FailsafeConfig{Action: "strobe", After: "idle"}},
+	}
+	for want, e := range cases {
+		if err := ValidateEmitter(e); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v", want, err)
+		}
+	}
+}
+
+func TestOSCConfig(t *testing.T) {
+	var o OSCConfig
+	if o.AddressPrefix() != "/penumbra" || o.Session() != "osc" {
+		t.Errorf("defaults: prefix %q session %q", o.AddressPrefix(), o.Session())
+	}
+	o = OSCConfig{Port: 9000, Prefix: "/td/"}
+	if err := ValidateOSC(o); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if o.AddressPrefix() != "/td" {
+		t.Errorf("AddressPrefix() = %q, want /td", o.AddressPrefix())
+	}
+	cases := map[string]OSCConfig{
+		"out of range":    {Port: 70000},
+		"must start with": {Prefix: "penumbra"},
+	}
+	for want, o := range cases {
+		if err := ValidateOSC(o); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v", want, err)
+		}
+	}
+}
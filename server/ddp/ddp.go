@@ -0,0 +1,175 @@
+// Package ddp sends pixel data to WLED devices using the Distributed Display
+// Protocol. Unlike E1.31, DDP carries an arbitrary-length buffer, so every DDP
+// universe on a device is concatenated into one logical pixel buffer.
+package ddp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/dmx"
+	"github.com/footgunz/penumbra/output"
+)
+
+const (
+	Port       = 4048
+	HeaderSize = 10
+	// MaxData is the largest payload per packet: 480 RGB pixels, which keeps
+	// packets under a 1500-byte Ethernet MTU.
+	MaxData = 1440
+
+	flagVersion1 = 0x40
+	flagPush     = 0x01
+	typeRGB8     = 0x0B // RGB, 8 bits per channel
+	idDisplay    = 0x01 // default output device
+)
+
+func init() {
+	output.Register("ddp", func(cfg *config.Config, resolve config.ChannelCountResolver) (output.Driver, error) {
+		return NewDriver(resolve), nil
+	})
+}
+
+// Driver sends every universe whose protocol is "ddp" to its DeviceIP.
+// Universes sharing a DeviceIP form one buffer: they are laid out in universe
+// order, each occupying as many channels as its patches reach (the full 512
+// if it has no patches). Whenever any of a device's universes is due, the
+// whole buffer is sent, with the push flag on the last packet so WLED shows
+// it in one go.
+type Driver struct {
+	resolve   config.ChannelCountResolver
+	frames    map[int]output.Frame // last frame per DDP universe
+	sequences map[string]uint8     // per device
+	udp       *output.UDPSender
+}
+
+// NewDriver creates a DDP driver. resolve looks up fixture channel counts
+// for patch extents.
+func NewDriver(resolve config.ChannelCountResolver) *Driver {
+	return &Driver{
+		resolve:   resolve,
+		frames:    make(map[int]output.Frame),
+		sequences: make(map[string]uint8),
+		udp:       output.NewUDPSender("udp4", Port),
+	}
+}
+
+// Handles reports whether u is sent over DDP.
+func (d *Driver) Handles(u config.UniverseConfig) bool {
+	return u.OutputProtocol() == config.ProtocolDDP
+}
+
+// Send updates the buffered universes and transmits the buffer of every
+// device with a due frame.
+func (d *Driver) Send(frames []output.Frame) error {
+	devices := make(map[string]bool)
+	for _, f := range frames {
+		d.frames[f.Universe] = f
+		devices[f.Config.DeviceIP] = true
+	}
+
+	ips := make([]string, 0, len(devices))
+	for ip := range devices {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	var errs []error
+	for _, ip := range ips {
+		buf := buildBuffer(d.deviceFrames(ip), d.resolve)
+		for _, pkt := range buildPackets(buf, d.nextSeq(ip)) {
+			if err := d.udp.Send(ip, pkt); err != nil {
+				errs = append(errs, fmt.Errorf("ddp %s: %w", ip, err))
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Terminate forgets the given universes. DDP has no stream termination, so
+// the device simply stops receiving them.
+func (d *Driver) Terminate(frames []output.Frame) error {
+	for _, f := range frames {
+		delete(d.frames, f.Universe)
+	}
+	return nil
+}
+
+// Close releases the sockets.
+func (d *Driver) Close() error {
+	return d.udp.Close()
+}
+
+// deviceFrames returns the buffered frames addressed to ip, in universe order.
+func (d *Driver) deviceFrames(ip string) []output.Frame {
+	var frames []output.Frame
+	for _, f := range d.frames {
+		if f.Config.DeviceIP == ip {
+			frames = append(frames, f)
+		}
+	}
+	sort.Slice(frames, func(i, j int) bool { return frames[i].Universe < frames[j].Universe })
+	return frames
+}
+
+// nextSeq returns the next DDP sequence number for a device. Sequence runs
+// 1–15; 0 means "not used".
+func (d *Driver) nextSeq(ip string) uint8 {
+	seq := d.sequences[ip]%15 + 1
+	d.sequences[ip] = seq
+	return seq
+}
+
+// extent returns how many channels of u's frame belong in the device buffer:
+// the end of its highest patch, or the whole universe if it has no patches.
+func extent(u config.UniverseConfig, resolve config.ChannelCountResolver) int {
+	if len(u.Patches) == 0 {
+		return dmx.UniverseSize
+	}
+	end := 0
+	for _, p := range u.Patches {
+		if e := p.StartAddress + p.ChannelCount(resolve) - 1; e > end {
+			end = e
+		}
+	}
+	return min(end, dmx.UniverseSize)
+}
+
+// buildBuffer concatenates frames (in the order given) into one pixel
+// buffer, each contributing its patched extent.
+func buildBuffer(frames []output.Frame, resolve config.ChannelCountResolver) []byte {
+	var buf []byte
+	for _, f := range frames {
+		buf = append(buf, f.Data[:extent(f.Config, resolve)]...)
+	}
+	return buf
+}
+
+// buildPackets splits buf into DDP packets of at most MaxData bytes, each
+// addressed by its byte offset. The last packet carries the push flag.
+func buildPackets(buf []byte, seq uint8) [][]byte {
+	var pkts [][]byte
+	for offset := 0; offset < len(buf) || offset == 0; offset += MaxData {
+		end := min(offset+MaxData, len(buf))
+		pkt := make([]byte, HeaderSize+end-offset)
+
+		pkt[0] = flagVersion1                                   // Flags: version 1
+		pkt[1] = seq & 0x0f                                     // Sequence
+		pkt[2] = typeRGB8                                       // Data type
+		pkt[3] = idDisplay                                      // Destination ID
+		binary.BigEndian.PutUint32(pkt[4:], uint32(offset))     // Data offset (bytes)
+		binary.BigEndian.PutUint16(pkt[8:], uint16(end-offset)) // Data length
+		copy(pkt[HeaderSize:], buf[offset:end])
+
+		pkts = append(pkts, pkt)
+		if end == len(buf) {
+			break
+		}
+	}
+	pkts[len(pkts)-1][0] |= flagPush
+	return pkts
+}
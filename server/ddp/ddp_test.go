@@ -0,0 +1,73 @@
+package ddp
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/output"
+)
+
+func TestBuildBuffer_PatchExtents(t *testing.T) {
+	resolve := func(key string) int {
+		if key == "rgb-strip-100" {
+			return 300
+		}
+		return 0
+	}
+	a := make([]byte, 512)
+	a[0], a[299] = 0x01, 0x02
+	b := make([]byte, 512)
+	b[0], b[2] = 0x03, 0x04
+
+	frames := []output.Frame{
+		{Universe: 1, Data: a, Config: config.UniverseConfig{Patches: []config.Patch{
+			{FixtureKey: "rgb-strip-100", StartAddress: 1},
+		}}},
+		{Universe: 2, Data: b, Config: config.UniverseConfig{Patches: []config.Patch{
+			{FixtureKey: "manual", StartAddress: 1, Channels: []string{"r", "g", "b"}},
+		}}},
+	}
+
+	buf := buildBuffer(frames, resolve)
+
+	if len(buf) != 303 {
+		t.Fatalf("buffer length = %d, want 303", len(buf))
+	}
+	if buf[0] != 0x01 || buf[299] != 0x02 || buf[300] != 0x03 || buf[302] != 0x04 {
+		t.Errorf("universes misplaced: % x ... % x", buf[:3], buf[297:])
+	}
+}
+
+func TestBuildPackets_Split(t *testing.T) {
+	buf := make([]byte, 1800) // 600 RGB pixels
+	buf[1440] = 0xaa
+
+	pkts := buildPackets(buf, 5)
+
+	if len(pkts) != 2 {
+		t.Fatalf("packets = %d, want 2", len(pkts))
+	}
+	first, last := pkts[0], pkts[1]
+	if first[0] != flagVersion1 || last[0] != flagVersion1|flagPush {
+		t.Errorf("flags = %#x/%#x, want push only on last", first[0], last[0])
+	}
+	if first[1] != 5 || first[2] != typeRGB8 || first[3] != idDisplay {
+		t.Errorf("header = % x", first[:4])
+	}
+	if got := binary.BigEndian.Uint32(first[4:]); got != 0 {
+		t.Errorf("first offset = %d, want 0", got)
+	}
+	if got := binary.BigEndian.Uint16(first[8:]); got != MaxData {
+		t.Errorf("first length = %d, want %d", got, MaxData)
+	}
+	if got := binary.BigEndian.Uint32(last[4:]); got != MaxData {
+		t.Errorf("last offset = %d, want %d", got, MaxData)
+	}
+	if got := binary.BigEndian.Uint16(last[8:]); got != 360 {
+		t.Errorf("last length = %d, want 360", got)
+	}
+	if len(last) != HeaderSize+360 || last[HeaderSize] != 0xaa {
+		t.Errorf("last packet data misplaced")
+	}
+}
@@ -0,0 +1,146 @@
+package dmx
+
+import (
+	"math"
+	"strings"
+
+	"github.com/footgunz/penumbra/config"
+)
+
+// ColorValues returns the channels colour parameter cp sets on its patch p,
+// whose channel names are channels, for the given emitter state.
+//
+// The colour is converted to RGB. Brightness ("value") goes to the patch's
+// dimmer if it has one, so the colour mix keeps full resolution, and
+// otherwise scales the colour. A white channel takes the white part of the
+// mix (RGB→RGBW) and an amber channel the amber part of what is left
+// (RGBW→RGBAW); a "cct" colour on a fixture with warm and cool whites is
+// mixed from those alone.
+func ColorValues(cp config.ColorParam, p config.Patch, channels []string, state map[string]float64) []ChannelValue {
+	in := func(component string, def float64) float64 {
+		if param, ok := cp.Inputs[component]; ok {
+			return math.Max(0, math.Min(1, state[param]))
+		}
+		return def
+	}
+
+	var r, g, b float64
+	warmth := -1.0 // position from warm to cool white, for "cct"
+	switch cp.Model {
+	case config.ColorHSV:
+		r, g, b = hsvToRGB(in("hue", 0), in("saturation", 1))
+	case config.ColorRGB:
+		r, g, b = in("red", 0), in("green", 0), in("blue", 0)
+	case config.ColorCCT:
+		warmth = in("kelvin", 0)
+		lo, hi := cp.KelvinRange()
+		r, g, b = kelvinToRGB(lo + warmth*(hi-lo))
+	}
+	value := in("value", 1)
+
+	addr := make(map[string]int, len(channels)) // lower-case name -> address
+	for i, name := range channels {
+		if _, dup := addr[strings.ToLower(name)]; !dup {
+			addr[strings.ToLower(name)] = p.StartAddress + i
+		}
+	}
+	var out []ChannelValue
+	set := func(name string, v float64) {
+		if a, ok := addr[name]; ok {
+			out = append(out, ChannelValue{Channel: a, Value: FloatToDMX(v)})
+		}
+	}
+
+	dimmed := false
+	for i, name := range channels {
+		if !config.IsDimmer(name) {
+			continue
+		}
+		dimmed = true
+		ch := p.StartAddress + i
+		if fine, ok := addr[strings.ToLower(name+" fine")]; ok {
+			v16 := FloatToDMX16(value)
+			out = append(out,
+				ChannelValue{Channel: ch, Value: byte(v16 >> 8)},
+				ChannelValue{Channel: fine, Value: byte(v16), Fine: true})
+		} else {
+			out = append(out, ChannelValue{Channel: ch, Value: FloatToDMX(value)})
+		}
+	}
+	level := 1.0
+	if !dimmed {
+		level = value
+	}
+
+	_, warm := addr["warm white"]
+	_, cool := addr["cool white"]
+	if warmth >= 0 && warm && cool {
+		set("warm white", level*(1-warmth))
+		set("cool white", level*warmth)
+		r, g, b = 0, 0, 0
+	} else {
+		w := min(r, g, b)
+		if _, white := addr["white"]; white || warm || cool {
+			r, g, b = r-w, g-w, b-w
+		}
+		for _, name := range []string{"white", "warm white", "cool white"} {
+			set(name, level*w)
+		}
+	}
+	if _, amber := addr["amber"]; amber {
+		// Amber mixes as full red and half green.
+		a := min(r, 2*g)
+		r, g = r-a, g-a/2
+		set("amber", level*a)
+	}
+	set("red", level*r)
+	set("green", level*g)
+	set("blue", level*b)
+	return out
+}
+
+// hsvToRGB converts a hue (0–1 around the colour wheel) and saturation at
+// full value to RGB.
+func hsvToRGB(h, s float64) (r, g, b float64) {
+	h = math.Mod(h, 1) * 6
+	f := h - math.Floor(h)
+	p, q, t := 1-s, 1-s*f, 1-s*(1-f)
+	switch int(h) {
+	case 0:
+		return 1, t, p
+	case 1:
+		return q, 1, p
+	case 2:
+		return p, 1, t
+	case 3:
+		return p, q, 1
+	case 4:
+		return t, p, 1
+	default:
+		return 1, p, q
+	}
+}
+
+// kelvinToRGB approximates the colour of a black body at temperature k
+// (1000–40000 K), scaled so its brightest component is 1.
+func kelvinToRGB(k float64) (r, g, b float64) {
+	t := math.Max(1000, math.Min(40000, k)) / 100
+	if t <= 66 {
+		r = 255
+		g = 99.4708025861*math.Log(t) - 161.1195681661
+	} else {
+		r = 329.698727446 * math.Pow(t-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(t-60, -0.0755148492)
+	}
+	switch {
+	case t >= 66:
+		b = 255
+	case t <= 19:
+		b = 0
+	default:
+		b = 138.5177312231*math.Log(t-10) - 305.0447927307
+	}
+	r, g, b = math.Max(0, math.Min(255, r)), math.Max(0, math.Min(255, g)), math.Max(0, math.Min(255, b))
+	peak := max(r, g, b)
+	return r / peak, g / peak, b / peak
+}
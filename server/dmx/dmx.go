@@ -0,0 +1,210 @@
+// Package dmx turns normalised parameter state into per-universe DMX frames.
+// It is shared by every output transport so they all agree on channel values.
+package dmx
+
+import (
+	"math"
+
+	"github.com/footgunz/penumbra/config"
+)
+
+// UniverseSize is the number of channels in a DMX universe.
+const UniverseSize = 512
+
+// Render partitions state into universes. Every universe in cfg.Universes
+// gets a frame; targets in unconfigured universes are ignored. Mapped
+// channels follow their parameter (0 if the emitter hasn't sent it yet);
+// the others hold their patch default (see config.Patch.ChannelDefaults) or
+// 0. Colour parameters (cfg.Colors) then set their patches' colour channels.
+// resolve may be nil, in which case only manual patches are known.
+func Render(state map[string]float64, cfg *config.Config, resolve config.FixtureResolver) map[int][]byte {
+	resolve = orNone(resolve)
+	universes := defaults(cfg, resolve)
+	for paramName, targets := range cfg.Parameters {
+		value := state[paramName]
+		for _, t := range targets {
+			frame, ok := universes[t.Universe]
+			if !ok {
+				continue
+			}
+			setChannels(frame, TargetValues(t, value), false)
+		}
+	}
+	for _, cp := range cfg.Colors {
+		if frame, ok := universes[cp.Universe]; ok {
+			if p, ok := cfg.ColorPatch(cp); ok {
+				setChannels(frame, ColorValues(cp, p, p.ChannelNames(resolve), state), false)
+			}
+		}
+	}
+	return universes
+}
+
+// Blackout is Render with every mapped channel at DMX 0, bypassing target
+// transforms (an inverted target would otherwise go to full). Unmapped
+// channels keep their defaults.
+func Blackout(cfg *config.Config, resolve config.FixtureResolver) map[int][]byte {
+	resolve = orNone(resolve)
+	universes := defaults(cfg, resolve)
+	for _, targets := range cfg.Parameters {
+		for _, t := range targets {
+			frame, ok := universes[t.Universe]
+			if !ok {
+				continue
+			}
+			for _, ch := range []int{t.Channel, t.Fine()} {
+				if ch >= 1 && ch <= UniverseSize {
+					frame[ch-1] = 0
+				}
+			}
+		}
+	}
+	for _, cp := range cfg.Colors {
+		if frame, ok := universes[cp.Universe]; ok {
+			if p, ok := cfg.ColorPatch(cp); ok {
+				setChannels(frame, ColorValues(cp, p, p.ChannelNames(resolve), nil), true)
+			}
+		}
+	}
+	return universes
+}
+
+// setChannels writes values into frame, or 0s if zero is set.
+func setChannels(frame []byte, values []ChannelValue, zero bool) {
+	for _, cv := range values {
+		ch := cv.Channel - 1 // channel is 1-indexed
+		if ch >= 0 && ch < UniverseSize {
+			frame[ch] = cv.Value
+			if zero {
+				frame[ch] = 0
+			}
+		}
+	}
+}
+
+// orNone returns resolve, or a resolver that knows no fixtures if it is nil.
+func orNone(resolve config.FixtureResolver) config.FixtureResolver {
+	if resolve == nil {
+		return func(string) ([]string, map[string]int) { return nil, nil }
+	}
+	return resolve
+}
+
+// defaults returns a frame per configured universe holding its patch defaults.
+func defaults(cfg *config.Config, resolve config.FixtureResolver) map[int][]byte {
+	universes := make(map[int][]byte, len(cfg.Universes))
+	for id, u := range cfg.Universes {
+		frame := make([]byte, UniverseSize)
+		for _, p := range u.Patches {
+			for addr, v := range p.ChannelDefaults(resolve) {
+				if addr >= 1 && addr <= UniverseSize {
+					frame[addr-1] = v
+				}
+			}
+		}
+		universes[id] = frame
+	}
+	return universes
+}
+
+// ChannelValue is the DMX value a target sets on one channel.
+type ChannelValue struct {
+	Channel int // 1-indexed
+	Value   byte
+	Fine    bool // fine byte of a 16-bit target
+}
+
+// TargetValues returns the channels t sets for the normalised value v, after
+// t's transform: one for an 8-bit target, coarse then fine for a 16-bit one.
+func TargetValues(t config.ChannelTarget, v float64) []ChannelValue {
+	v = Transform(t, v)
+	if !t.Is16Bit() {
+		return []ChannelValue{{Channel: t.Channel, Value: FloatToDMX(v)}}
+	}
+	v16 := FloatToDMX16(v)
+	return []ChannelValue{
+		{Channel: t.Channel, Value: byte(v16 >> 8)},
+		{Channel: t.Fine(), Value: byte(v16), Fine: true},
+	}
+}
+
+// Transform applies t's response curve, invert flag and output range to the
+// normalised value v, returning a normalised value.
+func Transform(t config.ChannelTarget, v float64) float64 {
+	v = math.Max(0, math.Min(1, v))
+	switch t.Curve {
+	case config.CurveSquare:
+		v = v * v
+	case config.CurveGamma:
+		gamma := t.Gamma
+		if gamma == 0 {
+			gamma = config.DefaultGamma
+		}
+		v = math.Pow(v, gamma)
+	case config.CurveSCurve:
+		v = v * v * (3 - 2*v) // smoothstep
+	case config.CurveLUT:
+		v = lookup(t.LUT, v)
+	}
+	if t.Invert {
+		v = 1 - v
+	}
+	lo, hi := t.OutputRange()
+	return lo + v*(hi-lo)
+}
+
+// lookup interpolates linearly between table entries spaced evenly over 0–1.
+func lookup(table []float64, v float64) float64 {
+	if len(table) < 2 {
+		return v
+	}
+	pos := v * float64(len(table)-1)
+	i := int(pos)
+	if i >= len(table)-1 {
+		return table[len(table)-1]
+	}
+	frac := pos - float64(i)
+	return table[i] + frac*(table[i+1]-table[i])
+}
+
+// Blend mixes frame from toward frame to by p (0–1), channel by channel.
+// Channels of 16-bit targets in targets are blended as one 16-bit value, so
+// the fine byte doesn't wrap on its way across.
+func Blend(from, to []byte, p float64, targets map[string][]config.ChannelTarget) []byte {
+	out := make([]byte, len(to))
+	for i := range to {
+		var a float64
+		if i < len(from) {
+			a = float64(from[i])
+		}
+		out[i] = byte(math.Round(a + (float64(to[i])-a)*p))
+	}
+	for _, tt := range targets {
+		for _, t := range tt {
+			if !t.Is16Bit() {
+				continue
+			}
+			c, f := t.Channel-1, t.Fine()-1
+			if c < 0 || f < 0 || c >= len(to) || f >= len(to) || c >= len(from) || f >= len(from) {
+				continue
+			}
+			a := float64(uint16(from[c])<<8 | uint16(from[f]))
+			b := float64(uint16(to[c])<<8 | uint16(to[f]))
+			v := uint16(math.Round(a + (b-a)*p))
+			out[c], out[f] = byte(v>>8), byte(v)
+		}
+	}
+	return out
+}
+
+// FloatToDMX clamps a normalised 0–1 value and scales it to 0–255.
+func FloatToDMX(v float64) byte {
+	clamped := math.Max(0, math.Min(1, v))
+	return byte(math.Round(clamped * 255))
+}
+
+// FloatToDMX16 clamps a normalised 0–1 value and scales it to 0–65535.
+func FloatToDMX16(v float64) uint16 {
+	clamped := math.Max(0, math.Min(1, v))
+	return uint16(math.Round(clamped * 65535))
+}
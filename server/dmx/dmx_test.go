@@ -0,0 +1,181 @@
+package dmx
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/footgunz/penumbra/config"
+)
+
+func TestRender_16Bit(t *testing.T) {
+	cfg := &config.Config{
+		Universes: map[int]config.UniverseConfig{1: {}},
+		Parameters: map[string]config.ParameterConfig{
+			"pan":  {{Universe: 1, Channel: 1, Width: 16}},
+			"tilt": {{Universe: 1, Channel: 3, Width: 16, FineChannel: 10}},
+			"dim":  {{Universe: 1, Channel: 5}},
+		},
+	}
+
+	frame := Render(map[string]float64{"pan": 0.5, "tilt": 1, "dim": 0.5}, cfg, nil)[1]
+
+	// 0.5 * 65535 = 32767.5 → 32768 = 0x8000
+	if frame[0] != 0x80 || frame[1] != 0x00 {
+		t.Errorf("pan = %#x/%#x, want 0x80/0x00", frame[0], frame[1])
+	}
+	if frame[2] != 0xff || frame[9] != 0xff || frame[3] != 0 {
+		t.Errorf("tilt = %#x/%#x (ch4 %#x), want 0xff/0xff on ch3/ch10", frame[2], frame[9], frame[3])
+	}
+	if frame[4] != 128 {
+		t.Errorf("dim = %d, want 128", frame[4])
+	}
+
+	// The blackout scene zeroes both bytes of a 16-bit target.
+	frame = Render(map[string]float64{"pan": 0, "tilt": 0, "dim": 0}, cfg, nil)[1]
+	if frame[0] != 0 || frame[1] != 0 || frame[2] != 0 || frame[9] != 0 {
+		t.Errorf("blackout left 16-bit channels set: % x", frame[:10])
+	}
+}
+
+func TestTransform(t *testing.T) {
+	cases := []struct {
+		name   string
+		target config.ChannelTarget
+		in     float64
+		want   float64
+	}{
+		{"linear", config.ChannelTarget{}, 0.5, 0.5},
+		{"range", config.ChannelTarget{Max: 0.7}, 1, 0.7},
+		{"invert", config.ChannelTarget{Invert: true}, 0.25, 0.75},
+		{"invert range", config.ChannelTarget{Invert: true, Min: 0.2, Max: 0.6}, 0, 0.6},
+		{"square", config.ChannelTarget{Curve: config.CurveSquare}, 0.5, 0.25},
+		{"gamma", config.ChannelTarget{Curve: config.CurveGamma, Gamma: 3}, 0.5, 0.125},
+		{"scurve", config.ChannelTarget{Curve: config.CurveSCurve}, 0.25, 0.15625},
+		{"lut", config.ChannelTarget{Curve: config.CurveLUT, LUT: []float64{0, 0.8, 1}}, 0.25, 0.4},
+		{"lut end", config.ChannelTarget{Curve: config.CurveLUT, LUT: []float64{0, 0.8, 1}}, 1, 1},
+		{"clamped", config.ChannelTarget{}, 1.5, 1},
+	}
+	for _, c := range cases {
+		if got := Transform(c.target, c.in); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s: Transform(%g) = %g, want %g", c.name, c.in, got, c.want)
+		}
+	}
+}
+
+func TestRender_Defaults(t *testing.T) {
+	resolve := func(key string) ([]string, map[string]int) {
+		return []string{"Dimmer", "Red", "Mode", "Program"}, map[string]int{"Mode": 10, "Dimmer": 200}
+	}
+	cfg := &config.Config{
+		Universes: map[int]config.UniverseConfig{1: {Patches: []config.Patch{
+			{FixtureKey: "par", StartAddress: 11, Defaults: map[string]int{"Program": 42}},
+		}}},
+		Parameters: map[string]config.ParameterConfig{
+			"dim": {{Universe: 1, Channel: 11, Invert: true}},
+		},
+	}
+
+	frame := Render(map[string]float64{}, cfg, resolve)[1]
+	// Dimmer is mapped, so its default is ignored; unsent means 0 → inverted 255.
+	if frame[10] != 255 || frame[11] != 0 || frame[12] != 10 || frame[13] != 42 {
+		t.Errorf("render = % d, want [255 0 10 42]", frame[10:14])
+	}
+
+	frame = Blackout(cfg, resolve)[1]
+	if frame[10] != 0 || frame[12] != 10 || frame[13] != 42 {
+		t.Errorf("blackout = % d, want [0 0 10 42]", frame[10:14])
+	}
+}
+
+func TestBlend(t *testing.T) {
+	from := []byte{0, 0xff, 0x00}
+	to := []byte{200, 0x01, 0x00}
+	targets := map[string][]config.ChannelTarget{"pan": {{Universe: 1, Channel: 2, Width: 16}}}
+
+	got := Blend(from, to, 0.5, targets)
+	// Channel 1 blends as 8-bit; channels 2–3 as one 16-bit value halfway
+	// between 0xff00 and 0x0100, not byte by byte.
+	if got[0] != 100 || got[1] != 0x80 || got[2] != 0x00 {
+		t.Fatalf("Blend = % x, want 64 80 00", got)
+	}
+	if got := Blend(from, to, 1, targets); !bytes.Equal(got, to) {
+		t.Fatalf("Blend at 1 = % x, want % x", got, to)
+	}
+}
+
+func TestColorValues(t *testing.T) {
+	values := func(cp config.ColorParam, channels []string, state map[string]float64) map[int]byte {
+		out := make(map[int]byte)
+		p := config.Patch{FixtureKey: "manual", StartAddress: 1, Channels: channels}
+		for _, cv := range ColorValues(cp, p, channels, state) {
+			out[cv.Channel] = cv.Value
+		}
+		return out
+	}
+	hsv := config.ColorParam{Model: config.ColorHSV, Inputs: map[string]string{"hue": "h", "saturation": "s", "value": "v"}}
+
+	// Pure green at half brightness on an RGB fixture: value scales the colour.
+	got := values(hsv, []string{"Red", "Green", "Blue"}, map[string]float64{"h": 1.0 / 3, "s": 1, "v": 0.5})
+	if got[1] != 0 || got[2] != 128 || got[3] != 0 {
+		t.Errorf("hsv green on RGB = %v, want 0 128 0", got)
+	}
+
+	// With a dimmer, value goes there and the colour stays at full.
+	got = values(hsv, []string{"Dimmer", "Red", "Green", "Blue", "Strobe"}, map[string]float64{"h": 0, "s": 1, "v": 0.5})
+	if got[1] != 128 || got[2] != 255 || got[3] != 0 || got[4] != 0 {
+		t.Errorf("hsv red with dimmer = %v, want 128 255 0 0", got)
+	}
+	if _, ok := got[5]; ok {
+		t.Error("strobe channel should not be driven")
+	}
+
+	// Desaturated colour on RGBW: the white part moves to the white channel.
+	rgb := config.ColorParam{Model: config.ColorRGB, Inputs: map[string]string{"red": "r", "green": "g", "blue": "b"}}
+	got = values(rgb, []string{"Red", "Green", "Blue", "White"}, map[string]float64{"r": 1, "g": 0.6, "b": 0.6})
+	if got[1] != 102 || got[2] != 0 || got[3] != 0 || got[4] != 153 {
+		t.Errorf("rgb on RGBW = %v, want 102 0 0 153", got)
+	}
+
+	// On RGBAW, the amber part of what is left after white moves to amber.
+	rgbaw := []string{"Red", "Green", "Blue", "Amber", "White"}
+	got = values(rgb, rgbaw, map[string]float64{"r": 1, "g": 0.8, "b": 0.6})
+	if got[1] != 0 || got[2] != 0 || got[3] != 0 || got[4] != 102 || got[5] != 153 {
+		t.Errorf("rgb on RGBAW = %v, want 0 0 0 102 153", got)
+	}
+	got = values(rgb, rgbaw, map[string]float64{"r": 1, "g": 0.25, "b": 0})
+	if got[1] != 128 || got[2] != 0 || got[3] != 0 || got[4] != 128 || got[5] != 0 {
+		t.Errorf("orange on RGBAW = %v, want 128 0 0 128 0", got)
+	}
+
+	// Colour temperature on warm/cool whites mixes just the whites.
+	cct := config.ColorParam{Model: config.ColorCCT, Inputs: map[string]string{"kelvin": "k"}}
+	got = values(cct, []string{"Warm White", "Cool White"}, map[string]float64{"k": 0.25})
+	if got[1] != 191 || got[2] != 64 {
+		t.Errorf("cct on warm/cool = %v, want 191 64", got)
+	}
+
+	// On RGB, warm is red-heavy and cool close to white.
+	warm := values(cct, []string{"Red", "Green", "Blue"}, map[string]float64{"k": 0})
+	cool := values(cct, []string{"Red", "Green", "Blue"}, map[string]float64{"k": 1})
+	if warm[1] != 255 || warm[3] >= warm[2] || cool[3] < 240 {
+		t.Errorf("cct on RGB: warm %v, cool %v", warm, cool)
+	}
+}
+
+func TestRender_Colors(t *testing.T) {
+	cfg := &config.Config{
+		Universes: map[int]config.UniverseConfig{1: {Patches: []config.Patch{
+			{FixtureKey: "manual", Label: "Par", StartAddress: 10, Channels: []string{"Red", "Green", "Blue"}},
+		}}},
+		Colors: map[string]config.ColorParam{
+			"par": {Universe: 1, Patch: "Par", Model: config.ColorRGB, Inputs: map[string]string{"red": "r"}},
+		},
+	}
+	if got := Render(map[string]float64{"r": 1}, cfg, nil)[1]; got[9] != 255 {
+		t.Errorf("Render: red = %d, want 255", got[9])
+	}
+	if got := Blackout(cfg, nil)[1]; got[9] != 0 {
+		t.Errorf("Blackout: red = %d, want 0", got[9])
+	}
+}
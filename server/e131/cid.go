@@ -0,0 +1,94 @@
+package e131
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/footgunz/penumbra/config"
+)
+
+// CIDFile is the name of the file, next to config.json, that holds the
+// persisted E1.31 component identifier.
+const CIDFile = "penumbra.cid"
+
+// LoadCID reads the CID stored at path as a UUID string. If the file does not
+// exist a new random CID is generated and written there, so the server keeps
+// the same sACN source identity across restarts.
+func LoadCID(path string) ([16]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		cid, err := parseCID(strings.TrimSpace(string(data)))
+		if err != nil {
+			return cid, fmt.Errorf("cid %s: %w", path, err)
+		}
+		return cid, nil
+	}
+	if !os.IsNotExist(err) {
+		return [16]byte{}, fmt.Errorf("cid %s: %w", path, err)
+	}
+	cid := generateCID()
+	if err := os.WriteFile(path, []byte(formatCID(cid)+"\n"), 0o644); err != nil {
+		return cid, fmt.Errorf("cid %s: %w", path, err)
+	}
+	return cid, nil
+}
+
+var (
+	sourceCIDOnce sync.Once
+	sourceCID     [16]byte
+)
+
+// SourceCID returns the CID Penumbra sends with, loaded once from CIDFile
+// next to cfg's config file. If it can't be loaded a temporary random CID is
+// used for the life of the process, so the driver and the receiver's
+// self-filter always agree.
+func SourceCID(cfg *config.Config) [16]byte {
+	sourceCIDOnce.Do(func() {
+		cid, err := LoadCID(filepath.Join(cfg.Dir(), CIDFile))
+		if err != nil {
+			log.Printf("e131: %v — using a temporary CID", err)
+			if cid == ([16]byte{}) {
+				cid = generateCID()
+			}
+		}
+		sourceCID = cid
+	})
+	return sourceCID
+}
+
+// formatCID renders cid in canonical UUID form (8-4-4-4-12 hex digits).
+func formatCID(cid [16]byte) string {
+	h := hex.EncodeToString(cid[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// parseCID accepts a UUID with or without dashes.
+func parseCID(s string) ([16]byte, error) {
+	var cid [16]byte
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != len(cid) {
+		return cid, fmt.Errorf("invalid CID %q", s)
+	}
+	copy(cid[:], b)
+	return cid, nil
+}
+
+// generateCID returns a random (version 4) UUID.
+func generateCID() [16]byte {
+	var cid [16]byte
+	if _, err := rand.Read(cid[:]); err != nil {
+		// Fallback: deterministic bytes (should not happen)
+		for i := range cid {
+			cid[i] = byte(i + 1)
+		}
+	}
+	cid[6] = (cid[6] & 0x0f) | 0x40 // version 4
+	cid[8] = (cid[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return cid
+}
@@ -1,87 +1,142 @@
 package e131
 
 import (
-	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
-	"math"
-	"net"
 
 	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/dmx"
+	"github.com/footgunz/penumbra/output"
 )
 
 const (
-	Port            = 5568
-	UniverseSize    = 512
-	PacketSize      = 126 + UniverseSize
+	Port           = 5568
+	UniverseSize   = dmx.UniverseSize
+	PacketSize     = 126 + UniverseSize
+	SyncPacketSize = 49
 )
 
+// Framing layer option bits.
+const (
+	optionStreamTerminated = 0x40
+)
+
+// terminateCount is how many Stream_Terminated packets are sent when a
+// universe stops, as recommended by E1.31 §6.2.6.
+const terminateCount = 3
+
 var acnPacketIdentifier = []byte{
 	0x41, 0x53, 0x43, 0x2d, 0x45, 0x31, 0x2e, 0x31,
 	0x37, 0x00, 0x00, 0x00,
 }
 
-// Dispatcher sends E1.31 packets to WLED devices.
-type Dispatcher struct {
-	sequences map[int]uint8   // per-universe sequence numbers
-	conns     map[string]*net.UDPConn
+func init() {
+	output.Register("e131", func(cfg *config.Config, _ config.ChannelCountResolver) (output.Driver, error) {
+		return NewDriver(cfg), nil
+	})
+}
+
+// Driver sends E1.31 packets for every universe whose protocol is "e131".
+// It keeps one long-lived socket per destination address.
+type Driver struct {
+	cfg *config.Config
+
+	sequences map[int]uint8 // per-universe sequence numbers
+	syncSeq   uint8         // sequence number for synchronization packets
+	udp       *output.UDPSender
 	cid       [16]byte
 }
 
-func NewDispatcher(cfg *config.Config) *Dispatcher {
-	cid := generateCID()
-	return &Dispatcher{
+// NewDriver creates a Driver that identifies itself with SourceCID, so
+// receivers see the same source across restarts.
+func NewDriver(cfg *config.Config) *Driver {
+	return &Driver{
+		cfg:       cfg,
 		sequences: make(map[int]uint8),
-		conns:     make(map[string]*net.UDPConn),
-		cid:       cid,
+		udp:       output.NewUDPSender("udp", Port),
+		cid:       SourceCID(cfg),
 	}
 }
 
-// Dispatch partitions state into universes and sends E1.31 packets.
-func (d *Dispatcher) Dispatch(state map[string]float64, cfg *config.Config) {
-	// Build per-universe DMX arrays
-	universes := make(map[int][]byte)
-	for paramName, value := range state {
-		targets, ok := cfg.Parameters[paramName]
-		if !ok {
-			continue
-		}
-		for _, t := range targets {
-			u := t.Universe
-			if _, exists := universes[u]; !exists {
-				universes[u] = make([]byte, UniverseSize)
+// Handles reports whether u is sent over E1.31.
+func (d *Driver) Handles(u config.UniverseConfig) bool {
+	return u.OutputProtocol() == config.ProtocolE131
+}
+
+// Send transmits one data packet per frame. If any synchronized
+// universe went out, a synchronization packet follows so receivers apply the
+// whole frame set at once.
+func (d *Driver) Send(frames []output.Frame) error {
+	var errs []error
+	syncDests := make(map[string]bool)
+	for _, f := range frames {
+		syncAddr := d.syncAddress(f.Config)
+		pkt := buildPacket(f.Universe, f.Data, d.nextSeq(f.Universe), d.cid, d.cfg.E131.SourceName, f.Config.E131Priority(), syncAddr, 0)
+		for _, addr := range destinations(f.Universe, f.Config) {
+			errs = append(errs, d.udp.Send(addr, pkt))
+			if syncAddr != 0 {
+				// Multicast receivers listen for sync on the sync universe's
+				// group; unicast receivers get it at the same address.
+				if addr == universeMulticastAddr(f.Universe) {
+					addr = universeMulticastAddr(int(syncAddr))
+				}
+				syncDests[addr] = true
 			}
-			ch := t.Channel - 1 // channel is 1-indexed
-			if ch >= 0 && ch < UniverseSize {
-				universes[u][ch] = floatToDMX(value)
+		}
+	}
+	if len(syncDests) > 0 {
+		d.syncSeq++
+		pkt := buildSyncPacket(uint16(d.cfg.E131.SyncUniverse), d.syncSeq, d.cid)
+		for addr := range syncDests {
+			errs = append(errs, d.udp.Send(addr, pkt))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Terminate sends Stream_Terminated packets for every frame so receivers
+// release the stream instead of holding the last look.
+func (d *Driver) Terminate(frames []output.Frame) error {
+	var errs []error
+	for _, f := range frames {
+		for i := 0; i < terminateCount; i++ {
+			pkt := buildPacket(f.Universe, f.Data, d.nextSeq(f.Universe), d.cid, d.cfg.E131.SourceName, f.Config.E131Priority(), 0, optionStreamTerminated)
+			for _, addr := range destinations(f.Universe, f.Config) {
+				errs = append(errs, d.udp.Send(addr, pkt))
 			}
 		}
 	}
+	return errors.Join(errs...)
+}
 
-	for universe, dmx := range universes {
-		seq := d.nextSeq(universe)
-		pkt := buildPacket(universe, dmx, seq, d.cid, "penumbra")
-		addr := universeMulticastAddr(universe)
-		d.send(addr, pkt)
+// Close releases the sockets.
+func (d *Driver) Close() error {
+	return d.udp.Close()
+}
+
+// syncAddress returns the synchronization universe for u, or 0 if the
+// universe does not take part in synchronization.
+func (d *Driver) syncAddress(u config.UniverseConfig) uint16 {
+	if d.cfg.E131.SyncUniverse == 0 || !u.Sync {
+		return 0
 	}
+	return uint16(d.cfg.E131.SyncUniverse)
 }
 
-func (d *Dispatcher) nextSeq(universe int) uint8 {
+func (d *Driver) nextSeq(universe int) uint8 {
 	d.sequences[universe] = (d.sequences[universe] + 1) & 0xff
 	return d.sequences[universe]
 }
 
-func (d *Dispatcher) send(addr string, pkt []byte) {
-	udpAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", addr, Port))
-	if err != nil {
-		return
-	}
-	conn, err := net.DialUDP("udp", nil, udpAddr)
-	if err != nil {
-		return
+// destinations returns every address a universe is sent to, according to its
+// delivery mode: the multicast group and/or the configured unicast targets.
+func destinations(universe int, u config.UniverseConfig) []string {
+	var addrs []string
+	if u.Multicast() {
+		addrs = append(addrs, universeMulticastAddr(universe))
 	}
-	defer conn.Close()
-	conn.Write(pkt)
+	return append(addrs, u.UnicastTargets()...)
 }
 
 // UniverseMulticastAddr returns the E1.31 multicast address for a universe.
@@ -90,45 +145,65 @@ func universeMulticastAddr(universe int) string {
 	return fmt.Sprintf("239.255.%d.%d", (universe>>8)&0xff, universe&0xff)
 }
 
-func floatToDMX(v float64) byte {
-	clamped := math.Max(0, math.Min(1, v))
-	return byte(math.Round(clamped * 255))
-}
-
-func buildPacket(universe int, data []byte, seq uint8, cid [16]byte, sourceName string) []byte {
+func buildPacket(universe int, data []byte, seq uint8, cid [16]byte, sourceName string, priority uint8, syncAddr uint16, options uint8) []byte {
 	buf := make([]byte, PacketSize)
 
 	// Root layer
-	binary.BigEndian.PutUint16(buf[0:], 0x0010)        // preamble size
-	binary.BigEndian.PutUint16(buf[2:], 0x0000)        // postamble size
-	copy(buf[4:], acnPacketIdentifier)                  // ACN PID
+	binary.BigEndian.PutUint16(buf[0:], 0x0010) // preamble size
+	binary.BigEndian.PutUint16(buf[2:], 0x0000) // postamble size
+	copy(buf[4:], acnPacketIdentifier)          // ACN PID
 	rootPDULen := uint16(PacketSize - 16)
 	binary.BigEndian.PutUint16(buf[16:], 0x7000|rootPDULen)
-	binary.BigEndian.PutUint32(buf[18:], 0x00000004)   // vector: VECTOR_ROOT_E131_DATA
-	copy(buf[22:], cid[:])                              // CID
+	binary.BigEndian.PutUint32(buf[18:], 0x00000004) // vector: VECTOR_ROOT_E131_DATA
+	copy(buf[22:], cid[:])                           // CID
 
 	// Framing layer
 	framingPDULen := uint16(PacketSize - 38)
 	binary.BigEndian.PutUint16(buf[38:], 0x7000|framingPDULen)
-	binary.BigEndian.PutUint32(buf[40:], 0x00000002)   // vector: VECTOR_E131_DATA_PACKET
+	binary.BigEndian.PutUint32(buf[40:], 0x00000002) // vector: VECTOR_E131_DATA_PACKET
 	nameBytes := encodeSourceName(sourceName)
-	copy(buf[44:], nameBytes)                           // source name (64 bytes)
-	buf[108] = 100                                      // priority
-	binary.BigEndian.PutUint16(buf[109:], 0)           // synchronization address
-	buf[111] = seq                                      // sequence number
-	buf[112] = 0                                        // options
+	copy(buf[44:], nameBytes)                       // source name (64 bytes)
+	buf[108] = priority                             // priority
+	binary.BigEndian.PutUint16(buf[109:], syncAddr) // synchronization address
+	buf[111] = seq                                  // sequence number
+	buf[112] = options                              // options
 	binary.BigEndian.PutUint16(buf[113:], uint16(universe))
 
 	// DMP layer
-	dmpPDULen := uint16(PacketSize - 114)
-	binary.BigEndian.PutUint16(buf[114:], 0x7000|dmpPDULen)
-	buf[116] = 0x02                                     // vector: VECTOR_DMP_SET_PROPERTY
-	buf[117] = 0xa1                                     // address type and data type
-	binary.BigEndian.PutUint16(buf[118:], 0x0000)      // first property address
-	binary.BigEndian.PutUint16(buf[120:], 0x0001)      // address increment
-	binary.BigEndian.PutUint16(buf[122:], UniverseSize+1) // property count
-	buf[124] = 0x00                                     // DMX start code
-	copy(buf[125:], data)
+	dmpPDULen := uint16(PacketSize - 115)
+	binary.BigEndian.PutUint16(buf[115:], 0x7000|dmpPDULen)
+	buf[117] = 0x02                                       // vector: VECTOR_DMP_SET_PROPERTY
+	buf[118] = 0xa1                                       // address type and data type
+	binary.BigEndian.PutUint16(buf[119:], 0x0000)         // first property address
+	binary.BigEndian.PutUint16(buf[121:], 0x0001)         // address increment
+	binary.BigEndian.PutUint16(buf[123:], UniverseSize+1) // property count
+	buf[125] = 0x00                                       // DMX start code
+	copy(buf[126:], data)
+
+	return buf
+}
+
+// buildSyncPacket builds an E1.31 synchronization packet (E1.31 §6.3) that
+// tells receivers listening on syncAddr to apply their buffered data.
+func buildSyncPacket(syncAddr uint16, seq uint8, cid [16]byte) []byte {
+	buf := make([]byte, SyncPacketSize)
+
+	// Root layer
+	binary.BigEndian.PutUint16(buf[0:], 0x0010) // preamble size
+	binary.BigEndian.PutUint16(buf[2:], 0x0000) // postamble size
+	copy(buf[4:], acnPacketIdentifier)          // ACN PID
+	rootPDULen := uint16(SyncPacketSize - 16)
+	binary.BigEndian.PutUint16(buf[16:], 0x7000|rootPDULen)
+	binary.BigEndian.PutUint32(buf[18:], 0x00000008) // vector: VECTOR_ROOT_E131_EXTENDED
+	copy(buf[22:], cid[:])                           // CID
+
+	// Synchronization framing layer
+	framingPDULen := uint16(SyncPacketSize - 38)
+	binary.BigEndian.PutUint16(buf[38:], 0x7000|framingPDULen)
+	binary.BigEndian.PutUint32(buf[40:], 0x00000001) // vector: VECTOR_E131_EXTENDED_SYNCHRONIZATION
+	buf[44] = seq                                    // sequence number
+	binary.BigEndian.PutUint16(buf[45:], syncAddr)   // synchronization address
+	binary.BigEndian.PutUint16(buf[47:], 0)          // reserved
 
 	return buf
 }
@@ -143,14 +218,3 @@ func encodeSourceName(name string) []byte {
 	}
 	return buf
 }
-
-func generateCID() [16]byte {
-	var cid [16]byte
-	if _, err := rand.Read(cid[:]); err != nil {
-		// Fallback: deterministic bytes (should not happen)
-		for i := range cid {
-			cid[i] = byte(i + 1)
-		}
-	}
-	return cid
-}
@@ -0,0 +1,130 @@
+package e131
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/footgunz/penumbra/config"
+)
+
+func TestBuildPacket_Layout(t *testing.T) {
+	data := make([]byte, UniverseSize)
+	data[0] = 0xff
+	data[511] = 0x80
+	var cid [16]byte
+	cid[0] = 0xaa
+
+	pkt := buildPacket(258, data, 7, cid, "house", 150, 999, optionStreamTerminated)
+
+	if len(pkt) != PacketSize {
+		t.Fatalf("packet length = %d, want %d", len(pkt), PacketSize)
+	}
+	if got := binary.BigEndian.Uint16(pkt[113:]); got != 258 {
+		t.Errorf("universe = %d, want 258", got)
+	}
+	if got := binary.BigEndian.Uint16(pkt[115:]) & 0x0fff; got != PacketSize-115 {
+		t.Errorf("DMP PDU length = %d, want %d", got, PacketSize-115)
+	}
+	if pkt[117] != 0x02 {
+		t.Errorf("DMP vector = %#x, want 0x02", pkt[117])
+	}
+	if got := binary.BigEndian.Uint16(pkt[123:]); got != UniverseSize+1 {
+		t.Errorf("property count = %d, want %d", got, UniverseSize+1)
+	}
+	if got := binary.BigEndian.Uint16(pkt[109:]); got != 999 {
+		t.Errorf("sync address = %d, want 999", got)
+	}
+	if pkt[108] != 150 {
+		t.Errorf("priority = %d, want 150", pkt[108])
+	}
+	if string(pkt[44:49]) != "house" || pkt[49] != 0 {
+		t.Errorf("source name = %q, want %q", pkt[44:50], "house")
+	}
+	if pkt[111] != 7 {
+		t.Errorf("sequence = %d, want 7", pkt[111])
+	}
+	if pkt[112] != optionStreamTerminated {
+		t.Errorf("options = %#x, want %#x", pkt[112], optionStreamTerminated)
+	}
+	if pkt[22] != 0xaa {
+		t.Errorf("CID not copied into root layer")
+	}
+	if pkt[125] != 0 || pkt[126] != 0xff || pkt[637] != 0x80 {
+		t.Errorf("start code/data misplaced: sc=%#x first=%#x last=%#x", pkt[125], pkt[126], pkt[637])
+	}
+}
+
+func TestBuildSyncPacket_Layout(t *testing.T) {
+	var cid [16]byte
+	cid[15] = 0x55
+
+	pkt := buildSyncPacket(999, 3, cid)
+
+	if len(pkt) != SyncPacketSize {
+		t.Fatalf("packet length = %d, want %d", len(pkt), SyncPacketSize)
+	}
+	if got := binary.BigEndian.Uint32(pkt[18:]); got != 0x00000008 {
+		t.Errorf("root vector = %#x, want VECTOR_ROOT_E131_EXTENDED", got)
+	}
+	if got := binary.BigEndian.Uint16(pkt[38:]) & 0x0fff; got != SyncPacketSize-38 {
+		t.Errorf("framing PDU length = %d, want %d", got, SyncPacketSize-38)
+	}
+	if got := binary.BigEndian.Uint32(pkt[40:]); got != 0x00000001 {
+		t.Errorf("framing vector = %#x, want VECTOR_E131_EXTENDED_SYNCHRONIZATION", got)
+	}
+	if pkt[44] != 3 {
+		t.Errorf("sequence = %d, want 3", pkt[44])
+	}
+	if got := binary.BigEndian.Uint16(pkt[45:]); got != 999 {
+		t.Errorf("sync address = %d, want 999", got)
+	}
+	if pkt[37] != 0x55 {
+		t.Errorf("CID not copied into root layer")
+	}
+}
+
+func TestLoadCID_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), CIDFile)
+
+	first, err := LoadCID(path)
+	if err != nil {
+		t.Fatalf("first load: %v", err)
+	}
+	second, err := LoadCID(path)
+	if err != nil {
+		t.Fatalf("second load: %v", err)
+	}
+	if first != second {
+		t.Fatalf("CID changed between loads: %s vs %s", formatCID(first), formatCID(second))
+	}
+	if first[6]>>4 != 4 {
+		t.Errorf("expected a version 4 UUID, got %s", formatCID(first))
+	}
+}
+
+func TestSourceCID_SharedOnLoadFailure(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(cfgPath, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, CIDFile), []byte("not a uuid\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The receiver's self-filter must see the CID the driver sends with,
+	// even when the persisted one can't be read.
+	cid := SourceCID(cfg)
+	if cid == ([16]byte{}) {
+		t.Fatalf("SourceCID() is the zero CID")
+	}
+	if d := NewDriver(cfg); d.cid != cid {
+		t.Errorf("driver CID %s, receiver CID %s", formatCID(d.cid), formatCID(cid))
+	}
+}
@@ -0,0 +1,413 @@
+package e131
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+)
+
+// SourceTimeout is how long a source may stay silent before it is dropped
+// (E1.31 §6.7.1, network data loss).
+const SourceTimeout = 2500 * time.Millisecond
+
+// optionPreviewData marks packets meant for visualisers, not live output.
+const optionPreviewData = 0x80
+
+// Source describes an external sACN source for the status message.
+type Source struct {
+	Universe int    `json:"universe"` // sACN universe the source was heard on
+	CID      string `json:"cid"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	LastSeen int64  `json:"last_seen"` // unix ms
+}
+
+// source is the last data received from one CID on one universe. changed
+// records when each channel last changed, for LTP merging.
+type source struct {
+	cid      [16]byte
+	name     string
+	priority uint8
+	seq      uint8
+	data     [UniverseSize]byte
+	changed  [UniverseSize]time.Time
+	lastSeen time.Time
+}
+
+// ownFrame tracks Penumbra's own rendered data per output universe, so LTP
+// can tell when it last changed.
+type ownFrame struct {
+	data    [UniverseSize]byte
+	changed [UniverseSize]time.Time
+}
+
+// Receiver listens for E1.31 data on every universe with a merge policy and
+// merges the sources it hears into Penumbra's own output. It implements
+// output.Processor; onChange is called with the affected output universes
+// whenever a source's data changes, appears or goes away.
+//
+// Only multicast input is supported. Packets carrying Penumbra's own CID are
+// ignored, so listening on a universe Penumbra also sends is safe.
+type Receiver struct {
+	cfg      *config.Config
+	cid      [16]byte
+	onChange func(universes []int)
+	now      func() time.Time
+
+	mu        sync.Mutex
+	listeners map[int]*net.UDPConn         // by sACN universe
+	sources   map[int]map[[16]byte]*source // by sACN universe, then CID
+	own       map[int]*ownFrame            // by output universe
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReceiver creates a receiver that ignores packets from cid (Penumbra's
+// own). Call Sync to start listening and Run to expire silent sources.
+func NewReceiver(cfg *config.Config, cid [16]byte, onChange func(universes []int)) *Receiver {
+	return &Receiver{
+		cfg:       cfg,
+		cid:       cid,
+		onChange:  onChange,
+		now:       time.Now,
+		listeners: make(map[int]*net.UDPConn),
+		sources:   make(map[int]map[[16]byte]*source),
+		own:       make(map[int]*ownFrame),
+		done:      make(chan struct{}),
+	}
+}
+
+// Sync joins the multicast group of every configured input universe and
+// leaves the others. Call at startup and after the universe list changes.
+func (r *Receiver) Sync() {
+	want := make(map[int]bool)
+	for id, u := range r.cfg.Universes {
+		if input := u.SACNInput(id); input != 0 {
+			want[input] = true
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for universe, conn := range r.listeners {
+		if !want[universe] {
+			conn.Close()
+			delete(r.listeners, universe)
+			delete(r.sources, universe)
+		}
+	}
+	for universe := range want {
+		if _, ok := r.listeners[universe]; ok {
+			continue
+		}
+		group, err := net.ResolveUDPAddr("udp4", fmt.Sprintf("%s:%d", universeMulticastAddr(universe), Port))
+		if err != nil {
+			log.Printf("e131: input universe %d: %v", universe, err)
+			continue
+		}
+		conn, err := net.ListenMulticastUDP("udp4", nil, group)
+		if err != nil {
+			log.Printf("e131: input universe %d: %v", universe, err)
+			continue
+		}
+		r.listeners[universe] = conn
+		go r.listen(universe, conn)
+		log.Printf("e131: listening for sACN on universe %d", universe)
+	}
+	for id := range r.own {
+		if u, ok := r.cfg.Universes[id]; !ok || u.SACNInput(id) == 0 {
+			delete(r.own, id)
+		}
+	}
+}
+
+// Run drops sources that have gone silent. Blocks until Close.
+func (r *Receiver) Run() {
+	ticker := time.NewTicker(SourceTimeout / 5)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.expire(r.now())
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// Close stops Run and every listener. Safe to call more than once.
+func (r *Receiver) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for universe, conn := range r.listeners {
+			conn.Close()
+			delete(r.listeners, universe)
+		}
+	})
+}
+
+// Sources returns every active external source, sorted by universe and name.
+func (r *Receiver) Sources() []Source {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := []Source{}
+	for universe, srcs := range r.sources {
+		for _, s := range srcs {
+			out = append(out, Source{
+				Universe: universe,
+				CID:      formatCID(s.cid),
+				Name:     s.name,
+				Priority: int(s.priority),
+				LastSeen: s.lastSeen.UnixMilli(),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Universe != out[j].Universe {
+			return out[i].Universe < out[j].Universe
+		}
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].CID < out[j].CID
+	})
+	return out
+}
+
+// Process merges the sources heard on u's input universe into data according
+// to u.Merge. Universes without a merge policy pass through unchanged.
+func (r *Receiver) Process(universe int, u config.UniverseConfig, data []byte) []byte {
+	input := u.SACNInput(universe)
+	if input == 0 {
+		return data
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	own := r.trackOwn(universe, data)
+	srcs := r.sources[input]
+	if len(srcs) == 0 {
+		return data
+	}
+
+	out := make([]byte, UniverseSize)
+	switch u.Merge {
+	case config.MergeLTP:
+		for ch := range out {
+			value, at := own.data[ch], own.changed[ch]
+			for _, s := range srcs {
+				if s.changed[ch].After(at) {
+					value, at = s.data[ch], s.changed[ch]
+				}
+			}
+			out[ch] = value
+		}
+	case config.MergePriority:
+		top := u.E131Priority()
+		for _, s := range srcs {
+			top = max(top, s.priority)
+		}
+		if u.E131Priority() == top {
+			copy(out, own.data[:])
+		}
+		for _, s := range srcs {
+			if s.priority == top {
+				mergeHTP(out, s.data[:])
+			}
+		}
+	default: // config.MergeHTP
+		copy(out, own.data[:])
+		for _, s := range srcs {
+			mergeHTP(out, s.data[:])
+		}
+	}
+	return out
+}
+
+// trackOwn records which of Penumbra's own channels changed. Must be called
+// with r.mu held.
+func (r *Receiver) trackOwn(universe int, data []byte) *ownFrame {
+	now := r.now()
+	own, ok := r.own[universe]
+	if !ok {
+		own = &ownFrame{}
+		r.own[universe] = own
+		for ch := range own.changed {
+			own.changed[ch] = now
+		}
+	}
+	for ch := 0; ch < UniverseSize && ch < len(data); ch++ {
+		if own.data[ch] != data[ch] {
+			own.data[ch] = data[ch]
+			own.changed[ch] = now
+		}
+	}
+	return own
+}
+
+func mergeHTP(dst, src []byte) {
+	for ch := range dst {
+		dst[ch] = max(dst[ch], src[ch])
+	}
+}
+
+// listen reads packets for one input universe until conn is closed.
+func (r *Receiver) listen(universe int, conn *net.UDPConn) {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("e131: input universe %d: %v", universe, err)
+			}
+			return
+		}
+		pkt, err := parseDataPacket(buf[:n])
+		if err != nil || pkt.universe != universe {
+			continue
+		}
+		if r.handle(pkt, r.now()) {
+			r.notify(universe)
+		}
+	}
+}
+
+// handle applies one data packet and reports whether the merged output of
+// its universe may have changed.
+func (r *Receiver) handle(pkt dataPacket, now time.Time) bool {
+	if pkt.cid == r.cid || pkt.options&optionPreviewData != 0 {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	srcs := r.sources[pkt.universe]
+	s, ok := srcs[pkt.cid]
+
+	if pkt.options&optionStreamTerminated != 0 {
+		if ok {
+			log.Printf("e131: source %q terminated universe %d", s.name, pkt.universe)
+			delete(srcs, pkt.cid)
+		}
+		return ok
+	}
+
+	if !ok {
+		if srcs == nil {
+			srcs = make(map[[16]byte]*source)
+			r.sources[pkt.universe] = srcs
+		}
+		s = &source{cid: pkt.cid, seq: pkt.seq - 1}
+		for ch := range s.changed {
+			s.changed[ch] = now
+		}
+		srcs[pkt.cid] = s
+		log.Printf("e131: source %q (priority %d) appeared on universe %d", pkt.name, pkt.priority, pkt.universe)
+	}
+
+	// E1.31 §6.7.2: discard packets up to 19 behind the last one received.
+	if diff := int8(pkt.seq - s.seq); diff <= 0 && diff > -20 {
+		return false
+	}
+	s.seq = pkt.seq
+	s.lastSeen = now
+	s.name = pkt.name
+
+	changed := !ok || s.priority != pkt.priority
+	s.priority = pkt.priority
+	var data [UniverseSize]byte
+	copy(data[:], pkt.data)
+	for ch := range data {
+		if s.data[ch] != data[ch] {
+			s.data[ch] = data[ch]
+			s.changed[ch] = now
+			changed = true
+		}
+	}
+	return changed
+}
+
+// expire drops sources not heard from within SourceTimeout.
+func (r *Receiver) expire(now time.Time) {
+	var lost []int
+	r.mu.Lock()
+	for universe, srcs := range r.sources {
+		for cid, s := range srcs {
+			if now.Sub(s.lastSeen) > SourceTimeout {
+				log.Printf("e131: source %q on universe %d timed out", s.name, universe)
+				delete(srcs, cid)
+				lost = append(lost, universe)
+			}
+		}
+	}
+	r.mu.Unlock()
+	for _, universe := range lost {
+		r.notify(universe)
+	}
+}
+
+// notify calls onChange with every output universe that merges input.
+func (r *Receiver) notify(input int) {
+	var universes []int
+	for id, u := range r.cfg.Universes {
+		if u.SACNInput(id) == input {
+			universes = append(universes, id)
+		}
+	}
+	if len(universes) > 0 && r.onChange != nil {
+		sort.Ints(universes)
+		r.onChange(universes)
+	}
+}
+
+// dataPacket is a parsed E1.31 data packet.
+type dataPacket struct {
+	cid      [16]byte
+	name     string
+	priority uint8
+	seq      uint8
+	options  uint8
+	universe int
+	data     []byte // channel slots, without the start code
+}
+
+var errNotDMX = errors.New("not an E1.31 DMX data packet")
+
+// parseDataPacket parses an E1.31 data packet with the NULL start code.
+// Other start codes (e.g. 0xDD per-address priority) are rejected.
+func parseDataPacket(buf []byte) (dataPacket, error) {
+	var pkt dataPacket
+	if len(buf) < 126 ||
+		!bytes.Equal(buf[4:16], acnPacketIdentifier) ||
+		binary.BigEndian.Uint32(buf[18:]) != 0x00000004 || // VECTOR_ROOT_E131_DATA
+		binary.BigEndian.Uint32(buf[40:]) != 0x00000002 || // VECTOR_E131_DATA_PACKET
+		buf[117] != 0x02 || buf[118] != 0xa1 { // VECTOR_DMP_SET_PROPERTY
+		return pkt, errNotDMX
+	}
+	count := int(binary.BigEndian.Uint16(buf[123:])) // start code + slots
+	if count < 1 || count > UniverseSize+1 || len(buf) < 125+count || buf[125] != 0x00 {
+		return pkt, errNotDMX
+	}
+	copy(pkt.cid[:], buf[22:38])
+	name := buf[44:108]
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	pkt.name = string(name)
+	pkt.priority = buf[108]
+	pkt.seq = buf[111]
+	pkt.options = buf[112]
+	pkt.universe = int(binary.BigEndian.Uint16(buf[113:]))
+	pkt.data = buf[126 : 125+count]
+	return pkt, nil
+}
@@ -0,0 +1,81 @@
+package e131
+
+import (
+	"testing"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+)
+
+func testPacket(t *testing.T, cid byte, seq, priority uint8, values ...byte) dataPacket {
+	t.Helper()
+	data := make([]byte, UniverseSize)
+	copy(data, values)
+	pkt, err := parseDataPacket(buildPacket(1, data, seq, [16]byte{cid}, "console", priority, 0, 0))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return pkt
+}
+
+func TestParseDataPacket_RoundTrip(t *testing.T) {
+	pkt := testPacket(t, 0xaa, 7, 150, 0x10, 0x20)
+	if pkt.cid[0] != 0xaa || pkt.name != "console" || pkt.priority != 150 || pkt.seq != 7 || pkt.universe != 1 {
+		t.Fatalf("header = %+v", pkt)
+	}
+	if len(pkt.data) != UniverseSize || pkt.data[0] != 0x10 || pkt.data[1] != 0x20 {
+		t.Fatalf("data misparsed")
+	}
+
+	sync := buildSyncPacket(1, 0, [16]byte{})
+	if _, err := parseDataPacket(sync); err == nil {
+		t.Fatalf("sync packet accepted as data")
+	}
+}
+
+func TestReceiver_Merge(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cfg := &config.Config{Universes: map[int]config.UniverseConfig{1: {Merge: config.MergeHTP}}}
+	r := NewReceiver(cfg, [16]byte{0xee}, nil)
+	r.now = func() time.Time { return now }
+	process := func(merge string, own ...byte) []byte {
+		data := make([]byte, UniverseSize)
+		copy(data, own)
+		return r.Process(1, config.UniverseConfig{Merge: merge}, data)
+	}
+
+	if r.handle(testPacket(t, 0xee, 1, 100, 0xff), now) {
+		t.Fatalf("own CID should be ignored")
+	}
+
+	process(config.MergeHTP, 100, 100) // own frame first seen at t=1000
+	now = now.Add(time.Second)
+	r.handle(testPacket(t, 0x01, 1, 100, 50, 200), now)
+
+	if out := process(config.MergeHTP, 100, 100); out[0] != 100 || out[1] != 200 {
+		t.Errorf("htp = %d,%d, want 100,200", out[0], out[1])
+	}
+	if out := process(config.MergeLTP, 100, 100); out[0] != 50 || out[1] != 200 {
+		t.Errorf("ltp after console = %d,%d, want 50,200", out[0], out[1])
+	}
+	now = now.Add(time.Second)
+	if out := process(config.MergeLTP, 120, 100); out[0] != 120 || out[1] != 200 {
+		t.Errorf("ltp after own change = %d,%d, want 120,200", out[0], out[1])
+	}
+
+	// A higher-priority source takes over entirely.
+	r.handle(testPacket(t, 0x02, 1, 150, 10), now)
+	if out := process(config.MergePriority, 120, 100); out[0] != 10 || out[1] != 0 {
+		t.Errorf("priority = %d,%d, want 10,0", out[0], out[1])
+	}
+
+	// Stale sequence numbers are discarded.
+	if r.handle(testPacket(t, 0x02, 1, 150, 99), now) {
+		t.Errorf("repeated sequence number accepted")
+	}
+
+	r.expire(now.Add(SourceTimeout + time.Millisecond))
+	if out := process(config.MergeHTP, 120, 100); out[0] != 120 || out[1] != 100 {
+		t.Errorf("after timeout = %d,%d, want own 120,100", out[0], out[1])
+	}
+}
@@ -0,0 +1,284 @@
+// Package enttec drives USB-DMX dongles on a local serial device, for small
+// shows that skip the network entirely. Two drivers are registered:
+//
+//   - "enttec-pro": Enttec DMX USB Pro framing. Each frame is one
+//     "Output Only Send DMX" (label 6) message; the widget generates the DMX
+//     signal itself and keeps repeating the last frame.
+//   - "open-dmx": raw Open DMX style output. The dongle is a bare UART, so the
+//     driver produces the break, mark-after-break and 250 kbaud slots itself
+//     and must keep transmitting continuously.
+//
+// A universe is bound to a dongle with protocol "enttec-pro" or "open-dmx"
+// and serial.device in config.json.
+package enttec
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/dmx"
+	"github.com/footgunz/penumbra/output"
+)
+
+// Enttec DMX USB Pro message framing.
+const (
+	proStart      = 0x7E
+	proEnd        = 0xE7
+	proLabelDMX   = 6 // Output Only Send DMX Packet Request
+	proHeaderSize = 4 // start, label, length LSB, length MSB
+)
+
+// Open DMX line timing. DMX512 requires a break of at least 92µs and a
+// mark-after-break of at least 12µs; both are padded for sleep jitter.
+const (
+	openDMXBaud    = 250000
+	proBaud        = 57600 // ignored by the Pro's USB interface
+	breakTime      = 176 * time.Microsecond
+	markAfterBreak = 16 * time.Microsecond
+)
+
+// startCode is the DMX512 NULL start code that precedes the channel slots.
+const startCode = 0x00
+
+func init() {
+	output.Register(config.ProtocolEnttecPro, func(cfg *config.Config, _ config.ChannelCountResolver) (output.Driver, error) {
+		return NewProDriver(), nil
+	})
+	output.Register(config.ProtocolOpenDMX, func(cfg *config.Config, _ config.ChannelCountResolver) (output.Driver, error) {
+		return NewOpenDMXDriver(time.Second / time.Duration(cfg.Output.RefreshHz)), nil
+	})
+}
+
+// ProDriver writes frames to Enttec DMX USB Pro widgets.
+type ProDriver struct {
+	ports map[string]*os.File // by device path
+}
+
+// NewProDriver creates an Enttec DMX USB Pro driver.
+func NewProDriver() *ProDriver {
+	return &ProDriver{ports: make(map[string]*os.File)}
+}
+
+// Handles reports whether u is an "enttec-pro" universe with a serial
+// device.
+func (d *ProDriver) Handles(u config.UniverseConfig) bool {
+	return u.OutputProtocol() == config.ProtocolEnttecPro && u.Serial != nil
+}
+
+// Send writes one label 6 message per frame.
+func (d *ProDriver) Send(frames []output.Frame) error {
+	var errs []error
+	for _, f := range frames {
+		device := f.Config.Serial.Device
+		port, err := d.port(device)
+		if err == nil {
+			_, err = port.Write(buildProPacket(f.Data))
+		}
+		if err != nil {
+			// Drop the port so an unplugged widget is reopened on the next frame.
+			if port != nil {
+				port.Close()
+				delete(d.ports, device)
+			}
+			errs = append(errs, fmt.Errorf("enttec-pro %s: %w", device, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases the serial devices.
+func (d *ProDriver) Close() error {
+	for device, port := range d.ports {
+		port.Close()
+		delete(d.ports, device)
+	}
+	return nil
+}
+
+// port returns the open serial device, opening it on first use.
+func (d *ProDriver) port(device string) (*os.File, error) {
+	if port, ok := d.ports[device]; ok {
+		return port, nil
+	}
+	port, err := openPort(device, proBaud)
+	if err != nil {
+		return nil, err
+	}
+	d.ports[device] = port
+	return port, nil
+}
+
+// buildProPacket wraps a frame in an Output Only Send DMX message: the
+// payload is the start code followed by the channel data.
+func buildProPacket(data []byte) []byte {
+	if len(data) > dmx.UniverseSize {
+		data = data[:dmx.UniverseSize]
+	}
+	length := 1 + len(data)
+	buf := make([]byte, 0, proHeaderSize+length+1)
+	buf = append(buf, proStart, proLabelDMX, byte(length), byte(length>>8), startCode)
+	buf = append(buf, data...)
+	return append(buf, proEnd)
+}
+
+// OpenDMXDriver transmits frames through Open DMX style dongles. Each device
+// gets a goroutine that sends the latest frame continuously, because the
+// dongle has no buffer of its own and fixtures treat a silent line as loss of
+// signal.
+type OpenDMXDriver struct {
+	interval time.Duration
+	lines    map[string]*line // by device path
+}
+
+// NewOpenDMXDriver creates an Open DMX driver that sends a frame every
+// interval on each device.
+func NewOpenDMXDriver(interval time.Duration) *OpenDMXDriver {
+	return &OpenDMXDriver{interval: interval, lines: make(map[string]*line)}
+}
+
+// Handles reports whether u is an "open-dmx" universe with a serial device.
+func (d *OpenDMXDriver) Handles(u config.UniverseConfig) bool {
+	return u.OutputProtocol() == config.ProtocolOpenDMX && u.Serial != nil
+}
+
+// Send updates the frame each device is transmitting, starting the device
+// on first use.
+func (d *OpenDMXDriver) Send(frames []output.Frame) error {
+	var errs []error
+	for _, f := range frames {
+		device := f.Config.Serial.Device
+		l, ok := d.lines[device]
+		if !ok || l.failed() {
+			if ok {
+				l.stop()
+			}
+			port, err := openPort(device, openDMXBaud)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("open-dmx %s: %w", device, err))
+				continue
+			}
+			l = startLine(device, port, f.Data, d.interval)
+			d.lines[device] = l
+			continue
+		}
+		l.set(f.Data)
+	}
+	return errors.Join(errs...)
+}
+
+// Terminate stops transmitting on the devices of the given universes, so
+// receivers see the signal drop.
+func (d *OpenDMXDriver) Terminate(frames []output.Frame) error {
+	for _, f := range frames {
+		if l, ok := d.lines[f.Config.Serial.Device]; ok {
+			l.stop()
+			delete(d.lines, f.Config.Serial.Device)
+		}
+	}
+	return nil
+}
+
+// Close stops every device.
+func (d *OpenDMXDriver) Close() error {
+	for device, l := range d.lines {
+		l.stop()
+		delete(d.lines, device)
+	}
+	return nil
+}
+
+// line is one Open DMX device and the goroutine transmitting on it.
+type line struct {
+	device string
+	port   *os.File
+
+	mu   sync.Mutex
+	slot []byte // start code + channel data
+	err  error
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func startLine(device string, port *os.File, data []byte, interval time.Duration) *line {
+	l := &line{device: device, port: port, done: make(chan struct{})}
+	l.set(data)
+	l.wg.Add(1)
+	go l.run(interval)
+	return l
+}
+
+// set replaces the frame being transmitted.
+func (l *line) set(data []byte) {
+	l.mu.Lock()
+	l.slot = buildOpenDMXFrame(data)
+	l.mu.Unlock()
+}
+
+// failed reports whether the transmit loop stopped on an error.
+func (l *line) failed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err != nil
+}
+
+func (l *line) run(interval time.Duration) {
+	defer l.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		l.mu.Lock()
+		slot := l.slot
+		l.mu.Unlock()
+		if err := writeFrame(l.port, slot); err != nil {
+			select {
+			case <-l.done:
+			default:
+				log.Printf("open-dmx %s: %v", l.device, err)
+			}
+			l.mu.Lock()
+			l.err = err
+			l.mu.Unlock()
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// stop ends transmission and closes the device. Closing the device also
+// unblocks a write in progress.
+func (l *line) stop() {
+	close(l.done)
+	l.port.Close()
+	l.wg.Wait()
+}
+
+// writeFrame sends one DMX packet: wait for the previous packet to leave the
+// UART, then break, mark-after-break and the slots.
+func writeFrame(port *os.File, slot []byte) error {
+	if err := drain(port); err != nil {
+		return err
+	}
+	if err := sendBreak(port, breakTime, markAfterBreak); err != nil {
+		return err
+	}
+	_, err := port.Write(slot)
+	return err
+}
+
+// buildOpenDMXFrame returns the start code followed by the channel data.
+func buildOpenDMXFrame(data []byte) []byte {
+	if len(data) > dmx.UniverseSize {
+		data = data[:dmx.UniverseSize]
+	}
+	return append([]byte{startCode}, data...)
+}
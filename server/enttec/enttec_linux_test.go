@@ -0,0 +1,116 @@
+package enttec
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/output"
+)
+
+// openPTY returns the master side of a new pseudo-terminal and the path of
+// its slave, which stands in for the dongle's serial device.
+func openPTY(t *testing.T) (*os.File, string) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminal support: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+	var n uint32
+	err = control(master, func(fd int) error {
+		if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+			return err
+		}
+		n, err = unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("pty setup: %v", err)
+	}
+	return master, "/dev/pts/" + strconv.Itoa(int(n))
+}
+
+func readN(t *testing.T, master *os.File, n int) []byte {
+	t.Helper()
+	master.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(master, buf); err != nil {
+		t.Fatalf("read %d bytes: %v", n, err)
+	}
+	return buf
+}
+
+func testFrame(protocol, device string) output.Frame {
+	data := make([]byte, 512)
+	data[0] = 0xAA
+	data[1] = 0x0A // a newline, to catch output processing
+	data[511] = 0xBB
+	return output.Frame{
+		Universe: 1,
+		Data:     data,
+		Config: config.UniverseConfig{
+			Type:     "usb",
+			Protocol: protocol,
+			Serial:   &config.Serial{Device: device},
+		},
+	}
+}
+
+func TestProDriver_WritesLabel6(t *testing.T) {
+	master, device := openPTY(t)
+	d := NewProDriver()
+	defer d.Close()
+
+	if err := d.Send([]output.Frame{testFrame(config.ProtocolEnttecPro, device)}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	want := make([]byte, 518)
+	copy(want, []byte{0x7E, 0x06, 0x01, 0x02, 0x00, 0xAA, 0x0A})
+	want[516] = 0xBB
+	want[517] = 0xE7
+	if got := readN(t, master, len(want)); !bytes.Equal(got, want) {
+		t.Fatalf("wrote\n% x\nwant\n% x", got, want)
+	}
+}
+
+func TestOpenDMXDriver_WritesFrame(t *testing.T) {
+	master, device := openPTY(t)
+	d := NewOpenDMXDriver(time.Hour) // one frame only
+	defer d.Close()
+
+	if err := d.Send([]output.Frame{testFrame(config.ProtocolOpenDMX, device)}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	want := make([]byte, 513)
+	copy(want, []byte{0x00, 0xAA, 0x0A})
+	want[512] = 0xBB
+	if got := readN(t, master, len(want)); !bytes.Equal(got, want) {
+		t.Fatalf("wrote\n% x\nwant\n% x", got, want)
+	}
+
+	slave, err := os.OpenFile(device, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Fatalf("open slave: %v", err)
+	}
+	defer slave.Close()
+	var tio *unix.Termios
+	err = control(slave, func(fd int) (err error) {
+		tio, err = unix.IoctlGetTermios(fd, unix.TCGETS2)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("termios: %v", err)
+	}
+	if tio.Ospeed != openDMXBaud || tio.Cflag&unix.CSTOPB == 0 || tio.Cflag&unix.CSIZE != unix.CS8 {
+		t.Errorf("line settings: speed %d, cflag %#o; want 250000 8N2", tio.Ospeed, tio.Cflag)
+	}
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+package enttec
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPort opens a serial device in raw mode at 8N2 and the given baud rate.
+// Arbitrary rates such as DMX's 250000 are set with termios2 (BOTHER).
+func openPort(device string, baud int) (*os.File, error) {
+	f, err := os.OpenFile(device, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	err = control(f, func(fd int) error {
+		t, err := unix.IoctlGetTermios(fd, unix.TCGETS2)
+		if err != nil {
+			return err
+		}
+		t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+			unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF
+		t.Oflag &^= unix.OPOST
+		t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+		t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CBAUD | unix.CRTSCTS
+		t.Cflag |= unix.CS8 | unix.CSTOPB | unix.CLOCAL | unix.CREAD | unix.BOTHER
+		t.Ispeed = uint32(baud)
+		t.Ospeed = uint32(baud)
+		return unix.IoctlSetTermios(fd, unix.TCSETS2, t)
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// drain blocks until everything written to port has been transmitted.
+func drain(port *os.File) error {
+	return control(port, func(fd int) error {
+		return unix.IoctlSetInt(fd, unix.TCSBRK, 1) // tcdrain
+	})
+}
+
+// sendBreak holds the line low for brk, then high for mab.
+func sendBreak(port *os.File, brk, mab time.Duration) error {
+	err := control(port, func(fd int) error {
+		if err := unix.IoctlSetInt(fd, unix.TIOCSBRK, 0); err != nil {
+			return err
+		}
+		time.Sleep(brk)
+		return unix.IoctlSetInt(fd, unix.TIOCCBRK, 0)
+	})
+	time.Sleep(mab)
+	return err
+}
+
+func control(f *os.File, fn func(fd int) error) error {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	if err := raw.Control(func(fd uintptr) { fnErr = fn(int(fd)) }); err != nil {
+		return err
+	}
+	return fnErr
+}
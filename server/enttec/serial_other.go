@@ -0,0 +1,17 @@
+//go:build !linux
+
+package enttec
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+var errUnsupported = errors.New("serial DMX output is only supported on Linux")
+
+func openPort(device string, baud int) (*os.File, error) { return nil, errUnsupported }
+
+func drain(port *os.File) error { return errUnsupported }
+
+func sendBreak(port *os.File, brk, mab time.Duration) error { return errUnsupported }
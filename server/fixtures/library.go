@@ -4,11 +4,12 @@ package fixtures
 
 // Fixture represents a single fixture at a single channel count.
 type Fixture struct {
-	Name         string   `json:"name"`
-	ShortName    string   `json:"shortName"`
-	Manufacturer string   `json:"manufacturer"`
-	ChannelCount int      `json:"channelCount"`
-	Channels     []string `json:"channels"`
+	Name         string         `json:"name"`
+	ShortName    string         `json:"shortName"`
+	Manufacturer string         `json:"manufacturer"`
+	ChannelCount int            `json:"channelCount"`
+	Channels     []string       `json:"channels"`
+	Defaults     map[string]int `json:"defaults,omitempty"` // channel name -> DMX value held when no parameter drives it
 }
 
 // Library maps fixture keys (e.g. "generic/rgb-3ch") to their definitions.
@@ -33,6 +34,7 @@ var Library = map[string]Fixture{
 		Manufacturer: "American DJ",
 		ChannelCount: 9,
 		Channels:     []string{"Red", "Green", "Blue", "UV", "Strobe", "Dimmer", "Program Mode", "Program Selection", "Program Speed"},
+		Defaults:     map[string]int{"Program Mode": 0, "Program Selection": 0, "Program Speed": 0},
 	},
 	"chauvet-dj/slimpar-pro-h-usb-12ch": {
 		Name:         "Chauvet DJ SlimPAR Pro H USB (12ch)",
@@ -40,6 +42,7 @@ var Library = map[string]Fixture{
 		Manufacturer: "Chauvet DJ",
 		ChannelCount: 12,
 		Channels:     []string{"Dimmer", "Red", "Green", "Blue", "Amber", "White", "UV", "Strobe", "Color Macros", "Mode", "Program Speed", "Dimmer Speed"},
+		Defaults:     map[string]int{"Color Macros": 0, "Mode": 0, "Program Speed": 0, "Dimmer Speed": 0},
 	},
 	"chauvet-dj/slimpar-pro-h-usb-6ch": {
 		Name:         "Chauvet DJ SlimPAR Pro H USB (6ch)",
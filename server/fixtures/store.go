@@ -50,3 +50,23 @@ func (s *Store) Add(key string, f Fixture) error {
 	s.fixtures[key] = f
 	return nil
 }
+
+// ChannelCount returns the channel count of the fixture with key, or 0 if it
+// is unknown. Usable as a config.ChannelCountResolver.
+func (s *Store) ChannelCount(key string) int {
+	f, ok := s.Get(key)
+	if !ok {
+		return 0
+	}
+	return f.ChannelCount
+}
+
+// Fixture returns the channel names and default values of the fixture with
+// key, or nil if it is unknown. Usable as a config.FixtureResolver.
+func (s *Store) Fixture(key string) ([]string, map[string]int) {
+	f, ok := s.Get(key)
+	if !ok {
+		return nil, nil
+	}
+	return f.Channels, f.Defaults
+}
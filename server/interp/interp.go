@@ -0,0 +1,282 @@
+// Package interp plays emitter state back at a steady rate. Packets arrive
+// whenever the network delivers them; the Engine places each state on the
+// emitter's own timeline (packet ts), delays playback by a small jitter
+// buffer, and renders the smoothed state on every output tick instead of on
+// every packet.
+package interp
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/udp"
+)
+
+const (
+	// maxSamples bounds the buffer if playback stalls behind arrivals.
+	maxSamples = 256
+	// driftRate is how quickly the clock offset follows a slower path: the
+	// fraction of the difference applied per packet. Faster arrivals are
+	// taken immediately, since they bound the network delay from below.
+	driftRate = 0.01
+	// settle is the distance below which an exponential ease snaps to its
+	// target, about a third of a 16-bit step.
+	settle = 5e-6
+	// clockReset is how far, in ms, a packet's ts may go back before it is
+	// taken as the emitter's clock restarting rather than a late packet.
+	clockReset = 5000
+)
+
+// Errors returned by Engine.Push for dropped packets.
+var (
+	ErrLate  = errors.New("interp: packet older than the last accepted")
+	ErrStale = errors.New("interp: packet arrived too late to play")
+)
+
+// Stats counts the packets an Engine has dropped and the emitter clock
+// resets it has followed.
+type Stats struct {
+	Late        uint64 `json:"late"`  // at or before the last accepted ts
+	Stale       uint64 `json:"stale"` // more than cfg.Emitter.StaleMs behind
+	ClockResets uint64 `json:"clock_resets"`
+}
+
+// sample is one received state at emitter time ts (unix ms).
+type sample struct {
+	ts    int64
+	state map[string]float64
+}
+
+// Engine buffers received states and renders them at cfg.Output.RefreshHz,
+// calling dispatch whenever the rendered state changes.
+type Engine struct {
+	cfg      *config.Config
+	dispatch func(map[string]float64)
+	now      func() time.Time
+
+	mu       sync.Mutex
+	session  string
+	samples  []sample // sorted by ts
+	offset   float64  // local ms minus emitter ts, for the fastest recent path
+	synced   bool     // offset has been estimated
+	lastTs   int64    // ts of the last accepted packet
+	stats    Stats
+	current  map[string]float64
+	lastTick time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates an engine that renders into dispatch. Call Run in a goroutine.
+func New(cfg *config.Config, dispatch func(map[string]float64)) *Engine {
+	return &Engine{
+		cfg:      cfg,
+		dispatch: dispatch,
+		now:      time.Now,
+		current:  make(map[string]float64),
+		done:     make(chan struct{}),
+	}
+}
+
+// Push buffers a received packet. A new session discards the buffer, so the
+// new session's first state is rendered without interpolating from the old;
+// so does a ts more than clockReset behind the last, taken as the emitter's
+// clock restarting. Otherwise a packet at or before the last accepted ts is
+// dropped with ErrLate, and one that arrived more than cfg.Emitter.StaleMs
+// behind the emitter's clock with ErrStale.
+func (e *Engine) Push(pkt udp.StatePacket) error {
+	now := e.now()
+	e.mu.Lock()
+	switch {
+	case pkt.SessionID != e.session:
+		e.session = pkt.SessionID
+		e.restart()
+	case pkt.Ts < e.lastTs-clockReset:
+		e.stats.ClockResets++
+		e.restart()
+	case pkt.Ts <= e.lastTs:
+		e.stats.Late++
+		e.mu.Unlock()
+		return ErrLate
+	default:
+		// Stale packets still count towards the offset, so a clock that
+		// falls behind is followed rather than dropped for good.
+		stale := e.cfg.Emitter.StaleMs > 0 && e.synced &&
+			millis(now)-e.offset-float64(pkt.Ts) > float64(e.cfg.Emitter.StaleMs)
+		e.observe(now, pkt.Ts)
+		if stale {
+			e.stats.Stale++
+			e.mu.Unlock()
+			return ErrStale
+		}
+	}
+	if !e.synced {
+		e.observe(now, pkt.Ts)
+	}
+	e.lastTs = pkt.Ts
+	e.insert(sample{ts: pkt.Ts, state: pkt.State})
+	e.mu.Unlock()
+
+	// Render straight away: with no jitter buffer the new state is already
+	// due, and there is no reason to wait for the next tick.
+	e.tick(now)
+	return nil
+}
+
+// Session returns the session ID of the last packet pushed.
+func (e *Engine) Session() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.session
+}
+
+// Stats returns the engine's drop and clock reset counters.
+func (e *Engine) Stats() Stats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stats
+}
+
+// State returns a copy of the last rendered state.
+func (e *Engine) State() map[string]float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return maps.Clone(e.current)
+}
+
+// Run renders on a fixed ticker. Blocks until Close.
+func (e *Engine) Run() {
+	ticker := time.NewTicker(time.Second / time.Duration(e.cfg.Output.RefreshHz))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			e.tick(now)
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// Close stops Run. Safe to call more than once.
+func (e *Engine) Close() {
+	e.closeOnce.Do(func() { close(e.done) })
+}
+
+// restart discards the buffer and the clock offset, for a new session or a
+// new emitter clock. Must be called with e.mu held.
+func (e *Engine) restart() {
+	e.samples = nil
+	e.synced = false
+}
+
+// observe updates the estimated offset between the local clock and the
+// emitter's from a packet stamped ts that arrived at now. Must be called
+// with e.mu held.
+func (e *Engine) observe(now time.Time, ts int64) {
+	d := millis(now) - float64(ts)
+	switch {
+	case !e.synced || d < e.offset:
+		e.offset = d
+		e.synced = true
+	default:
+		e.offset += (d - e.offset) * driftRate
+	}
+}
+
+// insert adds s in ts order, replacing a sample with the same ts. Must be
+// called with e.mu held.
+func (e *Engine) insert(s sample) {
+	i := sort.Search(len(e.samples), func(i int) bool { return e.samples[i].ts >= s.ts })
+	if i < len(e.samples) && e.samples[i].ts == s.ts {
+		e.samples[i] = s
+		return
+	}
+	e.samples = append(e.samples, sample{})
+	copy(e.samples[i+1:], e.samples[i:])
+	e.samples[i] = s
+	if len(e.samples) > maxSamples {
+		e.samples = e.samples[len(e.samples)-maxSamples:]
+	}
+}
+
+// tick renders the state due at now and dispatches it if it changed.
+func (e *Engine) tick(now time.Time) {
+	e.mu.Lock()
+	next, changed := e.render(now)
+	e.mu.Unlock()
+	if changed {
+		e.dispatch(next)
+	}
+}
+
+// render computes the state due at now, stores it as the current state and
+// returns a copy of it if it changed. Must be called with e.mu held.
+func (e *Engine) render(now time.Time) (map[string]float64, bool) {
+	dt := now.Sub(e.lastTick)
+	e.lastTick = now
+	if len(e.samples) == 0 {
+		return nil, false
+	}
+
+	// Playback position on the emitter's timeline.
+	t := millis(now) - e.offset - float64(e.cfg.Smoothing.JitterMs)
+
+	// a is the last sample at or before t (or the first, if playback hasn't
+	// reached the buffer yet); b follows it, if it has arrived.
+	i := sort.Search(len(e.samples), func(i int) bool { return float64(e.samples[i].ts) > t }) - 1
+	if i < 0 {
+		i = 0
+	}
+	e.samples = e.samples[i:] // older samples are never needed again
+	a := e.samples[0]
+	var b *sample
+	if len(e.samples) > 1 {
+		b = &e.samples[1]
+	}
+
+	tau := float64(e.cfg.Smoothing.TimeConstantMs)
+	next := make(map[string]float64, len(a.state))
+	params := make(map[string]float64)
+	maps.Copy(params, a.state)
+	if b != nil {
+		maps.Copy(params, b.state)
+	}
+	maps.Copy(params, e.current)
+	for param := range params {
+		// A parameter missing from a state is at 0, as in state.Mirror.
+		v := a.state[param]
+		switch e.cfg.Smoothing.ModeFor(param) {
+		case config.SmoothingLinear:
+			if b != nil && t > float64(a.ts) {
+				frac := (t - float64(a.ts)) / float64(b.ts-a.ts)
+				v += (b.state[param] - v) * math.Min(1, frac)
+			}
+		case config.SmoothingExponential:
+			cur, ok := e.current[param]
+			if ok && tau > 0 {
+				cur += (v - cur) * (1 - math.Exp(-dt.Seconds()*1000/tau))
+				if math.Abs(v-cur) > settle {
+					v = cur
+				}
+			}
+		}
+		next[param] = v
+	}
+
+	if maps.Equal(next, e.current) {
+		return nil, false
+	}
+	e.current = next
+	return maps.Clone(next), true
+}
+
+func millis(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Millisecond)
+}
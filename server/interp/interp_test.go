@@ -0,0 +1,151 @@
+package interp
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/udp"
+)
+
+// testEngine returns an engine on a fake clock and the states it dispatched.
+func testEngine(s config.SmoothingConfig) (*Engine, *time.Time, *[]map[string]float64) {
+	var sent []map[string]float64
+	cfg := &config.Config{Smoothing: s, Output: config.OutputConfig{RefreshHz: 44}}
+	e := New(cfg, func(state map[string]float64) { sent = append(sent, state) })
+	clock := time.UnixMilli(10_000)
+	e.now = func() time.Time { return clock }
+	return e, &clock, &sent
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestEngine_Linear(t *testing.T) {
+	e, clock, sent := testEngine(config.SmoothingConfig{
+		JitterMs:   40,
+		Mode:       config.SmoothingLinear,
+		Parameters: map[string]string{"strobe": config.SmoothingNone},
+	})
+
+	// Emitter ts runs 5000 ms behind the local clock.
+	e.Push(udp.StatePacket{SessionID: "s", Ts: 5000, State: map[string]float64{"dim": 0, "strobe": 0}})
+	*clock = clock.Add(40 * time.Millisecond)
+	e.Push(udp.StatePacket{SessionID: "s", Ts: 5040, State: map[string]float64{"dim": 1, "strobe": 1}})
+	*clock = clock.Add(10 * time.Millisecond)
+	e.tick(*clock)
+
+	// Playback is at 5000 + 50 - 40 = 5010: a quarter of the way to the
+	// second state, but the strobe holds its first value.
+	got := e.State()
+	if !near(got["dim"], 0.25) || got["strobe"] != 0 {
+		t.Fatalf("at ts 5010: %v, want dim 0.25 strobe 0", got)
+	}
+
+	e.tick(clock.Add(30 * time.Millisecond)) // ts 5040
+	got = e.State()
+	if got["dim"] != 1 || got["strobe"] != 1 {
+		t.Fatalf("at ts 5040: %v, want dim 1 strobe 1", got)
+	}
+
+	n := len(*sent)
+	e.tick(clock.Add(60 * time.Millisecond))
+	if len(*sent) != n {
+		t.Fatalf("unchanged state dispatched again")
+	}
+}
+
+func TestEngine_NoJitterRendersImmediately(t *testing.T) {
+	e, _, sent := testEngine(config.SmoothingConfig{Mode: config.SmoothingNone})
+	e.Push(udp.StatePacket{SessionID: "s", Ts: 1, State: map[string]float64{"dim": 0.5}})
+	if len(*sent) != 1 || (*sent)[0]["dim"] != 0.5 {
+		t.Fatalf("dispatched %v, want one state with dim 0.5", *sent)
+	}
+}
+
+func TestEngine_Exponential(t *testing.T) {
+	e, clock, _ := testEngine(config.SmoothingConfig{Mode: config.SmoothingExponential, TimeConstantMs: 100})
+	e.Push(udp.StatePacket{SessionID: "s", Ts: 0, State: map[string]float64{"dim": 0}})
+	*clock = clock.Add(10 * time.Millisecond)
+	e.Push(udp.StatePacket{SessionID: "s", Ts: 10, State: map[string]float64{"dim": 1}})
+
+	// 110 ms after the step (rendered when it arrived, then one tick) the
+	// ease has covered 1 - e^(-110/100) of it.
+	e.tick(clock.Add(100 * time.Millisecond))
+	if got, want := e.State()["dim"], 1-math.Exp(-1.1); !near(got, want) {
+		t.Fatalf("after 110 ms: %v, want %v", got, want)
+	}
+
+	e.tick(clock.Add(5 * time.Second))
+	if got := e.State()["dim"]; got != 1 {
+		t.Fatalf("ease did not settle: %v", got)
+	}
+}
+
+func TestEngine_SessionChange(t *testing.T) {
+	e, clock, _ := testEngine(config.SmoothingConfig{JitterMs: 40, Mode: config.SmoothingLinear})
+	e.Push(udp.StatePacket{SessionID: "a", Ts: 100, State: map[string]float64{"dim": 1}})
+	*clock = clock.Add(20 * time.Millisecond)
+
+	// The new session's clock is unrelated to the old one; its first state
+	// plays at once rather than waiting out (or blending with) the old buffer.
+	e.Push(udp.StatePacket{SessionID: "b", Ts: 900_000, State: map[string]float64{"dim": 0.2}})
+	if got := e.State()["dim"]; got != 0.2 {
+		t.Fatalf("after session change: %v, want 0.2", got)
+	}
+}
+
+func TestEngine_DropsLateAndStale(t *testing.T) {
+	e, clock, _ := testEngine(config.SmoothingConfig{Mode: config.SmoothingNone})
+	e.cfg.Emitter.StaleMs = 500
+
+	// Emitter ts runs 5000 ms behind the local clock.
+	if err := e.Push(udp.StatePacket{SessionID: "s", Ts: 5000, State: map[string]float64{"dim": 0.2}}); err != nil {
+		t.Fatal(err)
+	}
+	*clock = clock.Add(40 * time.Millisecond)
+	e.Push(udp.StatePacket{SessionID: "s", Ts: 5040, State: map[string]float64{"dim": 0.6}})
+
+	// A datagram overtaken on the way must not move the output backwards.
+	if err := e.Push(udp.StatePacket{SessionID: "s", Ts: 5020, State: map[string]float64{"dim": 0.4}}); !errors.Is(err, ErrLate) {
+		t.Fatalf("older packet: %v, want ErrLate", err)
+	}
+	if err := e.Push(udp.StatePacket{SessionID: "s", Ts: 5040, State: map[string]float64{"dim": 0.4}}); !errors.Is(err, ErrLate) {
+		t.Fatalf("duplicate packet: %v, want ErrLate", err)
+	}
+
+	// Sent at 5060 but held up for 600 ms.
+	*clock = clock.Add(620 * time.Millisecond)
+	if err := e.Push(udp.StatePacket{SessionID: "s", Ts: 5060, State: map[string]float64{"dim": 0.4}}); !errors.Is(err, ErrStale) {
+		t.Fatalf("delayed packet: %v, want ErrStale", err)
+	}
+	if got := e.State()["dim"]; got != 0.6 {
+		t.Errorf("dim = %v, want 0.6 from the last accepted packet", got)
+	}
+	if st := e.Stats(); st.Late != 2 || st.Stale != 1 || st.ClockResets != 0 {
+		t.Errorf("Stats() = %+v, want 2 late and 1 stale", st)
+	}
+}
+
+func TestEngine_ClockReset(t *testing.T) {
+	e, clock, _ := testEngine(config.SmoothingConfig{Mode: config.SmoothingNone})
+	e.cfg.Emitter.StaleMs = 500
+	e.Push(udp.StatePacket{SessionID: "s", Ts: 900_000, State: map[string]float64{"dim": 1}})
+
+	// The emitter restarts with the same session ID and a clock from zero.
+	*clock = clock.Add(3 * time.Second)
+	if err := e.Push(udp.StatePacket{SessionID: "s", Ts: 10, State: map[string]float64{"dim": 0.3}}); err != nil {
+		t.Fatalf("after clock reset: %v", err)
+	}
+	*clock = clock.Add(40 * time.Millisecond)
+	if err := e.Push(udp.StatePacket{SessionID: "s", Ts: 50, State: map[string]float64{"dim": 0.5}}); err != nil {
+		t.Fatalf("on the new clock: %v", err)
+	}
+	if got := e.State()["dim"]; got != 0.5 {
+		t.Errorf("dim = %v, want 0.5", got)
+	}
+	if st := e.Stats(); st.ClockResets != 1 {
+		t.Errorf("Stats() = %+v, want 1 clock reset", st)
+	}
+}
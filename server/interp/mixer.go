@@ -0,0 +1,344 @@
+package interp
+
+import (
+	"cmp"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/udp"
+)
+
+// EmitterStatus describes one emitter the Mixer is playing back.
+type EmitterStatus struct {
+	Source     string `json:"source"` // "ip:port"
+	Label      string `json:"label,omitempty"`
+	SessionID  string `json:"session_id"`
+	Namespace  string `json:"namespace,omitempty"`
+	Priority   int    `json:"priority"`
+	State      string `json:"state"`     // "connected", "idle" or "disconnected"
+	LastSeen   int64  `json:"last_seen"` // unix ms
+	Parameters int    `json:"parameters"`
+	Stats             // dropped packets and clock resets
+}
+
+// takeover is how long an emitter must have been silent for a new one from
+// the same IP to be taken as it restarting on a new port.
+const takeover = 500 * time.Millisecond
+
+// emitterKey identifies one emitter: a session from a source address.
+type emitterKey struct {
+	source  string
+	session string
+}
+
+// emitter is one session's playback.
+type emitter struct {
+	engine   *Engine
+	session  string
+	raw      map[string]float64   // last received state, namespaced
+	rendered map[string]float64   // last state the engine rendered
+	changed  map[string]time.Time // when each rendered parameter last changed, for LTP
+	lastSeen time.Time
+}
+
+// Mixer plays several emitters back at once. Each session from each source
+// address gets its own Engine, so every emitter keeps its own clock and
+// jitter buffer; their rendered states are merged per parameter by
+// cfg.Emitter.MergeRule and dispatched as one state. With a single emitter
+// it behaves exactly like that emitter's Engine.
+//
+// An emitter is retired, taking its parameters out of the merge, when its
+// source starts a new session, when a new source from its IP appears after
+// it has been silent for takeover (an emitter restarted on a new port), or
+// once it has been idle while another emitter is connected.
+type Mixer struct {
+	cfg      *config.Config
+	dispatch func(map[string]float64)
+	now      func() time.Time
+
+	mu       sync.Mutex
+	emitters map[emitterKey]*emitter
+	current  map[string]float64 // merged rendered state
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMixer creates a mixer that renders into dispatch. Call Run in a
+// goroutine.
+func NewMixer(cfg *config.Config, dispatch func(map[string]float64)) *Mixer {
+	return &Mixer{
+		cfg:      cfg,
+		dispatch: dispatch,
+		now:      time.Now,
+		emitters: make(map[emitterKey]*emitter),
+		current:  make(map[string]float64),
+		done:     make(chan struct{}),
+	}
+}
+
+// Replaces reports whether pkt starts an emitter that takes over from one
+// already playing: a new session from its source, or the same emitter
+// restarted on a new port. Callers crossfade into it; Push does the
+// takeover.
+func (m *Mixer) Replaces(pkt udp.StatePacket) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := emitterKey{pkt.Source, pkt.SessionID}
+	if _, ok := m.emitters[key]; ok {
+		return false
+	}
+	return len(m.replaced(key, m.now())) > 0
+}
+
+// Seen marks a packet's emitter as seen without buffering the packet, for
+// one dropped before it reaches Push.
+func (m *Mixer) Seen(pkt udp.StatePacket) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.emitters[emitterKey{pkt.Source, pkt.SessionID}]; ok {
+		e.lastSeen = m.now()
+	}
+}
+
+// Push buffers a received packet in its emitter's engine, prefixing its
+// parameters with the source's namespace. It returns the merged raw state
+// of every emitter as one packet, for state.Mirror. A packet the engine
+// drops (see Engine.Push) only marks its emitter as seen, and its error is
+// returned.
+func (m *Mixer) Push(pkt udp.StatePacket) (udp.StatePacket, error) {
+	src := m.cfg.Emitter.Source(pkt.Source)
+	pkt.State = namespaced(pkt.State, src.Namespace)
+	key := emitterKey{pkt.Source, pkt.SessionID}
+	now := m.now()
+
+	m.mu.Lock()
+	e, ok := m.emitters[key]
+	if !ok {
+		// The emitters this one replaces go before it renders, so no frame
+		// merges their last values with its first.
+		for _, old := range m.replaced(key, now) {
+			delete(m.emitters, old)
+		}
+		e = &emitter{session: pkt.SessionID, changed: make(map[string]time.Time)}
+		e.engine = New(m.cfg, func(state map[string]float64) { m.rendered(key, state) })
+		e.engine.now = m.now
+		m.emitters[key] = e
+	}
+	e.lastSeen = now
+	m.mu.Unlock()
+
+	if err := e.engine.Push(pkt); err != nil {
+		return udp.StatePacket{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.raw = pkt.State
+	return udp.StatePacket{SessionID: m.sessionID(), Ts: pkt.Ts, State: m.merge(func(e *emitter) map[string]float64 { return e.raw }), Source: pkt.Source}, nil
+}
+
+// replaced returns the emitters a new emitter key takes over from: other
+// sessions from its source, and silent emitters from its IP. Must be called
+// with m.mu held.
+func (m *Mixer) replaced(key emitterKey, now time.Time) []emitterKey {
+	host, _, _ := net.SplitHostPort(key.source)
+	var out []emitterKey
+	for k, e := range m.emitters {
+		h, _, _ := net.SplitHostPort(k.source)
+		switch {
+		case k.source == key.source:
+		case host != "" && h == host && now.Sub(e.lastSeen) >= takeover:
+		default:
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
+
+// State returns a copy of the last merged rendered state.
+func (m *Mixer) State() map[string]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.current)
+}
+
+// Emitters returns every emitter being played back, by source address.
+func (m *Mixer) Emitters() []EmitterStatus {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]EmitterStatus, 0, len(m.emitters))
+	for key, e := range m.emitters {
+		src := m.cfg.Emitter.Source(key.source)
+		out = append(out, EmitterStatus{
+			Source:     key.source,
+			Label:      src.Label,
+			SessionID:  e.session,
+			Namespace:  src.Namespace,
+			Priority:   src.EmitterPriority(),
+			State:      m.cfg.Emitter.State(now.Sub(e.lastSeen)).String(),
+			LastSeen:   e.lastSeen.UnixMilli(),
+			Parameters: len(e.raw),
+			Stats:      e.engine.Stats(),
+		})
+	}
+	slices.SortFunc(out, func(a, b EmitterStatus) int {
+		return cmp.Or(strings.Compare(a.Source, b.Source), strings.Compare(a.SessionID, b.SessionID))
+	})
+	return out
+}
+
+// Run renders every emitter on a fixed ticker and retires idle emitters
+// while another is connected. Blocks until Close.
+func (m *Mixer) Run() {
+	ticker := time.NewTicker(time.Second / time.Duration(m.cfg.Output.RefreshHz))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			m.tick(now)
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// Close stops Run. Safe to call more than once.
+func (m *Mixer) Close() {
+	m.closeOnce.Do(func() { close(m.done) })
+}
+
+func (m *Mixer) tick(now time.Time) {
+	m.mu.Lock()
+	if m.prune(now) {
+		m.update()
+	}
+	engines := make([]*Engine, 0, len(m.emitters))
+	for _, e := range m.emitters {
+		engines = append(engines, e.engine)
+	}
+	m.mu.Unlock()
+	for _, engine := range engines {
+		engine.tick(now)
+	}
+}
+
+// prune retires emitters that have gone idle if another emitter is still
+// connected, and reports whether it retired any. The last emitter is kept,
+// so its look holds. Must be called with m.mu held.
+func (m *Mixer) prune(now time.Time) bool {
+	var lost []emitterKey
+	for key, e := range m.emitters {
+		if m.cfg.Emitter.State(now.Sub(e.lastSeen)) != config.EmitterConnected {
+			lost = append(lost, key)
+		}
+	}
+	if len(lost) == 0 || len(lost) == len(m.emitters) {
+		return false
+	}
+	for _, key := range lost {
+		delete(m.emitters, key)
+	}
+	return true
+}
+
+// rendered records the state key's engine rendered and dispatches the new
+// merged state if it changed.
+func (m *Mixer) rendered(key emitterKey, state map[string]float64) {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.emitters[key]
+	if !ok {
+		return // retired while rendering
+	}
+	for param, v := range state {
+		if cur, ok := e.rendered[param]; !ok || cur != v {
+			e.changed[param] = now
+		}
+	}
+	e.rendered = state
+	m.update()
+}
+
+// update merges the rendered states and dispatches the result if it
+// changed. The dispatch happens with m.mu held, so merged states go out in
+// order. Must be called with m.mu held.
+func (m *Mixer) update() {
+	next := m.merge(func(e *emitter) map[string]float64 { return e.rendered })
+	if maps.Equal(next, m.current) {
+		return
+	}
+	m.current = next
+	m.dispatch(maps.Clone(next))
+}
+
+// merge resolves each parameter across the emitters' states by the merge
+// rule. A parameter an emitter doesn't send doesn't take part. Must be
+// called with m.mu held.
+func (m *Mixer) merge(states func(*emitter) map[string]float64) map[string]float64 {
+	if len(m.emitters) == 1 {
+		for _, e := range m.emitters {
+			return maps.Clone(states(e))
+		}
+	}
+	rule := m.cfg.Emitter.MergeRule()
+	out := make(map[string]float64)
+	won := make(map[string]int)      // priority of the winning emitter
+	at := make(map[string]time.Time) // change time of the winning value
+	for key, e := range m.emitters {
+		priority := m.cfg.Emitter.Source(key.source).EmitterPriority()
+		for param, v := range states(e) {
+			cur, seen := out[param]
+			switch {
+			case !seen:
+			case rule == config.MergeLTP:
+				if !e.changed[param].After(at[param]) {
+					continue
+				}
+			case rule == config.MergePriority && priority != won[param]:
+				if priority < won[param] {
+					continue
+				}
+			default: // HTP, or a priority tie
+				if v <= cur {
+					continue
+				}
+			}
+			out[param], won[param], at[param] = v, priority, e.changed[param]
+		}
+	}
+	return out
+}
+
+// sessionID returns the emitters' session IDs, sorted and joined with "+":
+// the single emitter's session ID, or a combined ID that changes whenever
+// an emitter starts or is retired. Must be called with m.mu held.
+func (m *Mixer) sessionID() string {
+	ids := make([]string, 0, len(m.emitters))
+	for _, e := range m.emitters {
+		ids = append(ids, e.session)
+	}
+	slices.Sort(ids)
+	return strings.Join(ids, "+")
+}
+
+// namespaced returns state with every parameter prefixed by ns, or state
+// itself if ns is empty.
+func namespaced(state map[string]float64, ns string) map[string]float64 {
+	if ns == "" {
+		return state
+	}
+	out := make(map[string]float64, len(state))
+	for param, v := range state {
+		out[ns+"."+param] = v
+	}
+	return out
+}
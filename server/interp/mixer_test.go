@@ -0,0 +1,156 @@
+package interp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/udp"
+)
+
+// testMixer returns a mixer on a fake clock with no smoothing and the
+// states it dispatched.
+func testMixer(e config.EmitterConfig) (*Mixer, *time.Time, *[]map[string]float64) {
+	var sent []map[string]float64
+	e.IdleTimeoutSec, e.DisconnectTimeoutSec = 5, 60
+	cfg := &config.Config{Emitter: e, Output: config.OutputConfig{RefreshHz: 44}}
+	m := NewMixer(cfg, func(state map[string]float64) { sent = append(sent, state) })
+	clock := time.UnixMilli(10_000)
+	m.now = func() time.Time { return clock }
+	return m, &clock, &sent
+}
+
+// packet returns a packet from source stamped at the fake clock's time.
+func packet(clock *time.Time, source, session string, state map[string]float64) udp.StatePacket {
+	return udp.StatePacket{SessionID: session, Ts: clock.UnixMilli(), State: state, Source: source}
+}
+
+func TestMixer_SingleEmitter(t *testing.T) {
+	m, clock, sent := testMixer(config.EmitterConfig{})
+	first := packet(clock, "10.0.0.5:5000", "a", map[string]float64{"dim": 0.5})
+	if m.Replaces(first) {
+		t.Errorf("first packet reported as replacing an emitter")
+	}
+	merged, _ := m.Push(first)
+	if merged.SessionID != "a" || merged.State["dim"] != 0.5 {
+		t.Fatalf("first packet: %+v", merged)
+	}
+	if len(*sent) != 1 || (*sent)[0]["dim"] != 0.5 {
+		t.Fatalf("dispatched %v, want dim 0.5", *sent)
+	}
+
+	// A new session from the same source replaces the old one.
+	next := packet(clock, "10.0.0.5:5000", "b", map[string]float64{"dim": 0.2})
+	if !m.Replaces(next) {
+		t.Errorf("session change not reported")
+	}
+	if merged, _ = m.Push(next); merged.SessionID != "b" || m.State()["dim"] != 0.2 {
+		t.Errorf("after new session: %+v, state %v", merged, m.State())
+	}
+}
+
+func TestMixer_RestartOnNewPort(t *testing.T) {
+	m, clock, _ := testMixer(config.EmitterConfig{})
+	m.Push(packet(clock, "10.0.0.5:5000", "a", map[string]float64{"dim": 0.9}))
+
+	// Another port on the same IP while the first is still sending is a
+	// second emitter.
+	other := packet(clock, "10.0.0.5:5001", "x", map[string]float64{"hue": 0.4})
+	if m.Replaces(other) {
+		t.Errorf("concurrent emitter on the same IP reported as a restart")
+	}
+	m.Push(other)
+
+	// The first emitter restarts on a new port with a new session.
+	*clock = clock.Add(time.Second)
+	m.Push(packet(clock, "10.0.0.5:5001", "x", map[string]float64{"hue": 0.4}))
+	restart := packet(clock, "10.0.0.5:6000", "b", map[string]float64{"dim": 0.2})
+	if !m.Replaces(restart) {
+		t.Errorf("restart on a new port not reported")
+	}
+	merged, _ := m.Push(restart)
+	if got := m.State()["dim"]; got != 0.2 {
+		t.Errorf("dim = %v, want 0.2 with the dead entry out of the HTP merge", got)
+	}
+	if merged.SessionID != "b+x" || len(m.Emitters()) != 2 {
+		t.Errorf("merged session %q, emitters %+v", merged.SessionID, m.Emitters())
+	}
+}
+
+func TestMixer_HTP(t *testing.T) {
+	m, clock, _ := testMixer(config.EmitterConfig{})
+	m.Push(packet(clock, "10.0.0.5:5000", "a", map[string]float64{"dim": 0.8, "hue": 0.1}))
+	second := packet(clock, "10.0.0.6:5000", "b", map[string]float64{"dim": 0.3, "hue": 0.6})
+	if m.Replaces(second) {
+		t.Errorf("second emitter reported as replacing the first")
+	}
+	merged, _ := m.Push(second)
+	if merged.SessionID != "a+b" {
+		t.Errorf("merged session = %q, want a+b", merged.SessionID)
+	}
+	if got := m.State(); got["dim"] != 0.8 || got["hue"] != 0.6 {
+		t.Errorf("HTP state = %v, want dim 0.8 hue 0.6", got)
+	}
+	if len(m.Emitters()) != 2 {
+		t.Errorf("Emitters() = %+v, want 2", m.Emitters())
+	}
+}
+
+func TestMixer_LTP(t *testing.T) {
+	m, clock, _ := testMixer(config.EmitterConfig{Merge: config.MergeLTP})
+	m.Push(packet(clock, "10.0.0.5:5000", "a", map[string]float64{"dim": 0.8}))
+	*clock = clock.Add(10 * time.Millisecond)
+	m.Push(packet(clock, "10.0.0.6:5000", "b", map[string]float64{"dim": 0.3}))
+	if got := m.State()["dim"]; got != 0.3 {
+		t.Fatalf("dim = %v, want the latest 0.3", got)
+	}
+
+	// Resending an unchanged value doesn't take control back.
+	*clock = clock.Add(10 * time.Millisecond)
+	m.Push(packet(clock, "10.0.0.5:5000", "a", map[string]float64{"dim": 0.8}))
+	if got := m.State()["dim"]; got != 0.3 {
+		t.Fatalf("dim = %v, want 0.3 after an unchanged resend", got)
+	}
+	*clock = clock.Add(10 * time.Millisecond)
+	m.Push(packet(clock, "10.0.0.5:5000", "a", map[string]float64{"dim": 0.7}))
+	if got := m.State()["dim"]; got != 0.7 {
+		t.Fatalf("dim = %v, want the latest 0.7", got)
+	}
+}
+
+func TestMixer_PriorityAndNamespace(t *testing.T) {
+	m, clock, _ := testMixer(config.EmitterConfig{
+		Merge: config.MergePriority,
+		Sources: map[string]config.EmitterSource{
+			"10.0.0.5":      {Priority: 50},
+			"10.0.0.7:5000": {Namespace: "deck"},
+		},
+	})
+	m.Push(packet(clock, "10.0.0.5:5000", "a", map[string]float64{"dim": 0.9}))
+	m.Push(packet(clock, "10.0.0.6:5000", "b", map[string]float64{"dim": 0.2}))
+	m.Push(packet(clock, "10.0.0.7:5000", "c", map[string]float64{"dim": 1}))
+	got := m.State()
+	if got["dim"] != 0.2 {
+		t.Errorf("dim = %v, want 0.2 from the higher-priority emitter", got["dim"])
+	}
+	if got["deck.dim"] != 1 {
+		t.Errorf("deck.dim = %v, want 1 from the namespaced emitter", got["deck.dim"])
+	}
+}
+
+func TestMixer_RetiresIdle(t *testing.T) {
+	m, clock, _ := testMixer(config.EmitterConfig{})
+	m.Push(packet(clock, "10.0.0.5:5000", "a", map[string]float64{"dim": 0.8}))
+	*clock = clock.Add(6 * time.Second)
+	m.Push(packet(clock, "10.0.0.6:5000", "b", map[string]float64{"dim": 0.3}))
+	m.tick(*clock)
+	if got := m.State()["dim"]; got != 0.3 {
+		t.Fatalf("dim = %v, want 0.3 once the idle emitter is retired", got)
+	}
+
+	// The last emitter is kept, so its look holds.
+	m.tick(clock.Add(120 * time.Second))
+	if n := len(m.Emitters()); n != 1 {
+		t.Fatalf("%d emitters, want the last one kept", n)
+	}
+}
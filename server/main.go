@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"maps"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,11 +21,21 @@ import (
 	"github.com/footgunz/penumbra/config"
 	"github.com/footgunz/penumbra/e131"
 	"github.com/footgunz/penumbra/fixtures"
+	"github.com/footgunz/penumbra/interp"
+	"github.com/footgunz/penumbra/osc"
+	"github.com/footgunz/penumbra/output"
+	"github.com/footgunz/penumbra/scene"
 	"github.com/footgunz/penumbra/state"
 	"github.com/footgunz/penumbra/tui"
 	"github.com/footgunz/penumbra/udp"
 	"github.com/footgunz/penumbra/wled"
 	"github.com/footgunz/penumbra/ws"
+
+	// Output drivers register themselves with the output package
+	// (e131 is imported above for sACN input).
+	_ "github.com/footgunz/penumbra/artnet"
+	_ "github.com/footgunz/penumbra/ddp"
+	_ "github.com/footgunz/penumbra/enttec"
 )
 
 func main() {
@@ -34,18 +51,62 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
+	if err := config.ValidateSmoothing(cfg.Smoothing); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	if err := cfg.ValidateSubmasters(); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	if err := cfg.ValidateSync(); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	if err := config.ValidateEmitter(cfg.Emitter); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	if err := config.ValidateCues(cfg.Cues); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	if err := config.ValidateOSC(cfg.OSC); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
 
 	hub := ws.NewHub(cfg)
 	go hub.Run()
 
 	// Create TUI program early so goroutine callbacks can reference it.
 	var program *tea.Program
+	var masters *output.Masters
+	var cues *scene.Cues
+	var mixer *interp.Mixer
 	if tuiMode {
 		m := tui.New(tui.BlackoutFuncs{
 			IsActive: hub.IsBlackout,
 			Trigger:  hub.Blackout,
+			EStop:    hub.EStop,
 			Reset:    hub.Reset,
-		})
+		}, tui.MasterFuncs{
+			Levels: func() (float64, map[string]float64) {
+				levels := masters.Levels()
+				return levels.Grand, levels.Submasters
+			},
+			Set: func(submaster string, level float64) {
+				if submaster == "" {
+					masters.SetGrand(level)
+				} else if err := masters.SetSubmaster(submaster, level); err != nil {
+					log.Printf("tui: %v", err)
+				}
+				hub.BroadcastStatus()
+			},
+		}, tui.CueFuncs{
+			Status: func() map[string]scene.CueStatus { return cues.Status() },
+			Command: func(list, command string) {
+				if err := cues.Command(list, command); err != nil {
+					log.Printf("tui: cue %s %s: %v", list, command, err)
+					return
+				}
+				hub.BroadcastStatus()
+			},
+		}, func() []interp.EmitterStatus { return mixer.Emitters() })
 		program = tea.NewProgram(m, tea.WithAltScreen())
 		log.SetOutput(tui.NewLogWriter(program))
 		log.SetFlags(log.Ltime)
@@ -55,58 +116,182 @@ func main() {
 		hub.Broadcast(diff.ToMessage())
 	})
 
-	dispatcher := e131.NewDispatcher(cfg)
+	fixtureStore := fixtures.NewStore()
+	if err := cfg.ValidateUniverses(fixtureStore.ChannelCount, fixtureStore.Fixture); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	if err := cfg.ValidateColors(fixtureStore.Fixture); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	hub.SetFixtures(fixtureStore.Fixture)
+	outputs, err := output.NewManager(cfg, fixtureStore)
+	if err != nil {
+		log.Fatalf("failed to start outputs: %v", err)
+	}
+	hub.SetOutputStats(outputs.Stats)
 
-	blackoutScene := func() map[string]float64 {
-		scene := cfg.BlackoutScene
-		if len(scene) == 0 {
-			scene = make(map[string]float64, len(cfg.Parameters))
-			for p := range cfg.Parameters {
-				scene[p] = 0
-			}
-		}
-		return scene
+	// Scenes are stored next to the config. Cue lists play them back over
+	// the live output, and are scaled by the masters like the rest of it.
+	sceneStore, err := scene.Open(filepath.Join(cfg.Dir(), scene.File))
+	if err != nil {
+		log.Fatalf("failed to load scenes: %v", err)
 	}
+	cues = scene.NewCues(sceneStore, cfg, fixtureStore.Fixture, outputs.Reprocess)
+	outputs.AddProcessor(cues)
+	hub.SetCues(cues)
+
+	// Masters scale Penumbra's own output, so they run before sACN merge.
+	masters = output.NewMasters(cfg, fixtureStore.Fixture, func() {
+		outputs.Reprocess(slices.Collect(maps.Keys(cfg.Universes)))
+	})
+	outputs.AddProcessor(masters)
+	hub.SetMasters(masters)
+
+	// sACN input: sources heard on merge-enabled universes are merged into
+	// the rendered frames before they are sent. Packets with the driver's
+	// CID are Penumbra's own output and are ignored.
+	sacnIn := e131.NewReceiver(cfg, e131.SourceCID(cfg), outputs.Reprocess)
+	outputs.AddProcessor(sacnIn)
+	hub.SetInputSources(sacnIn.Sources)
+
+	// Parked channels are applied last, so they hold through merge and blackout.
+	var parking *output.Parking
+	parking = output.NewParking(func(universes []int) {
+		outputs.Reprocess(universes)
+		if program != nil {
+			program.Send(parkedMsg(parking.Parked()))
+		}
+	})
+	outputs.AddProcessor(parking)
+	hub.SetParked(parking.Parked)
+
+	hub.SetOnBlackout(func(fade time.Duration) {
+		outputs.Fade(fade)
+		cues.Suspend(fade)
+		if len(cfg.BlackoutScene) == 0 {
+			// Zero every mapped channel, whatever its transform.
+			outputs.DispatchBlackout(cfg)
+		} else {
+			outputs.Dispatch(cfg.BlackoutScene, cfg)
+		}
+		if cfg.E131.TerminateOnBlackout {
+			// Terminate once the fade has finished, unless reset came first.
+			time.AfterFunc(fade, func() {
+				if hub.IsBlackout() {
+					outputs.Flush()
+					outputs.TerminateAll()
+				}
+			})
+		}
+	})
+	// The mixer plays each emitter's state back smoothly at the output rate
+	// and merges them into one state.
+	var scenes *scene.Player
+	var failsafe *scene.Failsafe
+	mixer = interp.NewMixer(cfg, func(state map[string]float64) {
+		if !hub.IsBlackout() && scenes.Held() == "" && !failsafe.Active() {
+			outputs.Dispatch(state, cfg)
+		}
+	})
+
+	// A held scene replaces live output until it is released.
+	scenes = scene.NewPlayer(sceneStore, cfg, outputs, scene.Sources{
+		Snapshot: func() map[string]float64 {
+			_, s, _ := stateMirror.Snapshot()
+			return s
+		},
+		Live:    mixer.State,
+		Blocked: hub.IsBlackout,
+	})
+	hub.SetHeldScene(scenes.Held)
+
+	// The failsafe takes over when the emitter goes quiet, unless blackout or
+	// a held scene already has.
+	failsafe = scene.NewFailsafe(sceneStore, cfg, outputs, scene.Sources{
+		Live: mixer.State,
+		Blocked: func() bool {
+			return hub.IsBlackout() || scenes.Held() != ""
+		},
+		Emitter: hub.EmitterState,
+	}, func(st scene.FailsafeStatus) {
+		hub.BroadcastStatus()
+		if program != nil {
+			program.Send(tui.FailsafeMsg(st))
+		}
+	})
+	hub.SetFailsafe(failsafe.Status)
+	hub.SetEmitters(mixer.Emitters)
 
-	hub.SetOnBlackout(func() {
-		dispatcher.Dispatch(blackoutScene(), cfg)
+	hub.SetOnReset(func(fade time.Duration) {
+		outputs.Fade(fade)
+		cues.Resume(fade)
+		if !scenes.Restore() && !failsafe.Apply() {
+			outputs.Dispatch(mixer.State(), cfg)
+		}
 	})
 
-	receiver := udp.NewReceiver(udpPort, func(pkt udp.StatePacket) {
+	// Every emitter packet marks its emitter seen before onPacket, including
+	// a delta the receiver drops instead.
+	seen := func(pkt udp.StatePacket) {
+		mixer.Seen(pkt)
 		hub.MaybebroadcastStatus(pkt.SessionID)
 		if program != nil {
 			program.Send(tui.EmitterSeenMsg{})
-			program.Send(tui.SessionMsg(pkt.SessionID))
 		}
+	}
 
+	// Emitter packets, from msgpack or OSC, all take the same path.
+	onPacket := func(pkt udp.StatePacket) {
+		// Arm the failsafe, or fade back from it when packets resume;
+		// otherwise crossfade into a new session's look instead of cutting to
+		// it, starting the fade before Push renders the new look. Keep
+		// buffering during blackout so reset resumes from the live state.
+		restored := failsafe.Restore()
+		if mixer.Replaces(pkt) && !restored && !hub.IsBlackout() && scenes.Held() == "" {
+			outputs.Fade(cfg.Fades.Session())
+		}
+		merged, err := mixer.Push(pkt)
+		if err != nil {
+			return // late or stale, counted in the emitter's status
+		}
+		if program != nil {
+			program.Send(tui.SessionMsg(merged.SessionID))
+		}
 		if hub.IsBlackout() {
 			return
 		}
 
-		changed := stateMirror.Update(pkt)
+		changed := stateMirror.Update(merged)
 		if changed {
-			dispatcher.Dispatch(pkt.State, cfg)
 			if program != nil {
-				program.Send(tui.ParamUpdateMsg(pkt.State))
+				program.Send(tui.ParamUpdateMsg(merged.State))
 			}
 		}
+	}
+	receiver := udp.NewReceiver(udpPort, onPacket)
+	receiver.SetOnSeen(seen)
+	hub.SetReceiverStats(receiver.Stats)
+	oscIn := osc.NewReceiver(cfg.OSC, func(pkt udp.StatePacket) {
+		seen(pkt)
+		onPacket(pkt)
 	})
+	hub.SetOSCStats(oscIn.Stats)
 
 	prober := wled.NewProber(cfg, func(id int, online bool) {
 		hub.SetUniverseOnline(id, online)
 		if program != nil {
-			label, ip := "", ""
-			if u, ok := cfg.Universes[id]; ok {
-				label = u.Label
-				ip = u.DeviceIP
-			}
-			program.Send(tui.UniverseMsg{ID: id, Label: label, IP: ip, Online: online})
+			u := cfg.Universes[id]
+			program.Send(universeMsg(id, u, online))
 		}
 	})
 
-	var onConfigUpdate func(*config.Config)
-	if program != nil {
-		onConfigUpdate = func(c *config.Config) {
+	onConfigUpdate := func(c *config.Config) {
+		sacnIn.Sync()
+		outputs.Prune(c)
+		if !hub.IsBlackout() && !scenes.Restore() && !failsafe.Apply() {
+			outputs.Dispatch(mixer.State(), c)
+		}
+		if program != nil {
 			program.Send(tui.EmitterTimeoutsMsg{
 				IdleTimeout:       time.Duration(c.Emitter.IdleTimeoutSec) * time.Second,
 				DisconnectTimeout: time.Duration(c.Emitter.DisconnectTimeoutSec) * time.Second,
@@ -114,26 +299,39 @@ func main() {
 			cm := make(tui.ConfigMsg, len(c.Parameters))
 			for param, targets := range c.Parameters {
 				tt := make([]tui.ChannelTarget, len(targets))
-				for i, t := range targets {
-					tt[i] = tui.ChannelTarget{Universe: t.Universe, Channel: t.Channel}
-				}
+				copy(tt, targets)
 				cm[param] = tt
 			}
 			program.Send(cm)
 			for id, u := range c.Universes {
-				program.Send(tui.UniverseMsg{ID: id, Label: u.Label, IP: u.DeviceIP})
+				program.Send(universeMsg(id, u, false))
 			}
 		}
 	}
 
-	fixtureStore := fixtures.NewStore()
-	router := api.NewRouter(hub, cfg, fixtureStore, wsPort, onConfigUpdate)
+	router := api.NewRouter(hub, cfg, fixtureStore, api.Controls{Parking: parking, Masters: masters, Scenes: scenes, Cues: cues}, wsPort, onConfigUpdate)
 
 	go hub.RunStatusTicker()
 
+	// SIGINT/SIGTERM terminate every output stream before exiting so E1.31
+	// receivers release the last look instead of holding it.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	shutdown := func() {
+		log.Printf("shutting down — terminating output streams")
+		sacnIn.Close()
+		mixer.Close()
+		cues.Close()
+		failsafe.Close()
+		outputs.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		router.Shutdown(ctx)
+	}
+
 	if tuiMode {
 		for id, u := range cfg.Universes {
-			go program.Send(tui.UniverseMsg{ID: id, Label: u.Label, IP: u.DeviceIP})
+			go program.Send(universeMsg(id, u, false))
 		}
 		go func() {
 			program.Send(tui.EmitterTimeoutsMsg{
@@ -143,32 +341,95 @@ func main() {
 			cm := make(tui.ConfigMsg, len(cfg.Parameters))
 			for param, targets := range cfg.Parameters {
 				tt := make([]tui.ChannelTarget, len(targets))
-				for i, t := range targets {
-					tt[i] = tui.ChannelTarget{Universe: t.Universe, Channel: t.Channel}
-				}
+				copy(tt, targets)
 				cm[param] = tt
 			}
 			program.Send(cm)
+			program.Send(tui.FailsafeMsg(failsafe.Status()))
 		}()
 		go receiver.Listen()
+		if cfg.OSC.Port != 0 {
+			go oscIn.Listen()
+		}
 		go prober.Run()
+		go outputs.Run()
+		go mixer.Run()
+		go cues.Run()
+		go failsafe.Run()
+		sacnIn.Sync()
+		go sacnIn.Run()
 		go func() {
 			log.Printf("Listening on :%d (UDP) and :%d (HTTP/WS)", udpPort, wsPort)
-			if err := router.ListenAndServe(); err != nil {
+			if err := router.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 				log.Printf("http: %v", err)
 			}
 		}()
+		go func() {
+			<-sig
+			program.Quit()
+		}()
 
-		if _, err := program.Run(); err != nil {
+		_, err := program.Run()
+		shutdown()
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "tui: %v\n", err)
 			os.Exit(1)
 		}
 	} else {
 		go receiver.Listen()
+		if cfg.OSC.Port != 0 {
+			go oscIn.Listen()
+		}
 		go prober.Run()
-		log.Printf("Listening on :%d (UDP) and :%d (HTTP/WS)", udpPort, wsPort)
-		log.Fatal(router.ListenAndServe())
+		go outputs.Run()
+		go mixer.Run()
+		go cues.Run()
+		go failsafe.Run()
+		sacnIn.Sync()
+		go sacnIn.Run()
+		go func() {
+			log.Printf("Listening on :%d (UDP) and :%d (HTTP/WS)", udpPort, wsPort)
+			if err := router.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+		<-sig
+		shutdown()
+	}
+}
+
+// universeMsg builds the TUI status message for a configured universe.
+func universeMsg(id int, u config.UniverseConfig, online bool) tui.UniverseMsg {
+	if u.Serial != nil {
+		// USB-DMX universes have no network delivery; show the device instead.
+		return tui.UniverseMsg{
+			ID:       id,
+			Label:    u.Label,
+			IP:       u.Serial.Device,
+			Delivery: u.OutputProtocol(),
+			Online:   online,
+		}
+	}
+	return tui.UniverseMsg{
+		ID:       id,
+		Label:    u.Label,
+		IP:       u.DeviceIP,
+		Delivery: u.DeliveryMode(),
+		Unicast:  u.UnicastTargets(),
+		Online:   online,
+	}
+}
+
+// parkedMsg builds the TUI parked-channel message.
+func parkedMsg(parked []output.ParkedChannel) tui.ParkedMsg {
+	msg := make(tui.ParkedMsg)
+	for _, p := range parked {
+		if msg[p.Universe] == nil {
+			msg[p.Universe] = make(map[int]int)
+		}
+		msg[p.Universe][p.Channel] = p.Value
 	}
+	return msg
 }
 
 func envInt(key string, fallback int) int {
@@ -0,0 +1,167 @@
+// Package osc receives Open Sound Control messages over UDP and turns them
+// into emitter state, for tools such as TouchDesigner and Resolume that
+// speak OSC rather than the msgpack emitter protocol.
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// bundleTag starts every OSC bundle.
+const bundleTag = "#bundle"
+
+// ErrMalformed is returned by Parse for data that isn't valid OSC.
+var ErrMalformed = errors.New("osc: malformed packet")
+
+// Message is one OSC message. Args holds int32, int64, float32, float64,
+// string, []byte, bool or nil values, by type tag.
+type Message struct {
+	Address string
+	Args    []any
+}
+
+// Parse decodes an OSC packet, a message or a bundle, and returns its
+// messages in order. Bundles are flattened, nested ones included; their
+// time tags are ignored and every message applies at once.
+func Parse(data []byte) ([]Message, error) {
+	var out []Message
+	if err := parse(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func parse(data []byte, out *[]Message) error {
+	if bytes.HasPrefix(data, []byte(bundleTag+"\x00")) {
+		// "#bundle\0", an 8-byte time tag, then size-prefixed elements.
+		if len(data) < 16 {
+			return ErrMalformed
+		}
+		data = data[16:]
+		for len(data) > 0 {
+			if len(data) < 4 {
+				return ErrMalformed
+			}
+			n := int(binary.BigEndian.Uint32(data))
+			data = data[4:]
+			if n < 0 || n > len(data) || n%4 != 0 {
+				return ErrMalformed
+			}
+			if err := parse(data[:n], out); err != nil {
+				return err
+			}
+			data = data[n:]
+		}
+		return nil
+	}
+
+	msg, err := parseMessage(data)
+	if err != nil {
+		return err
+	}
+	*out = append(*out, msg)
+	return nil
+}
+
+func parseMessage(data []byte) (Message, error) {
+	address, data, err := readString(data)
+	if err != nil || len(address) == 0 || address[0] != '/' {
+		return Message{}, ErrMalformed
+	}
+	msg := Message{Address: address}
+	if len(data) == 0 {
+		return msg, nil // no type tag string, as some old senders do
+	}
+	tags, data, err := readString(data)
+	if err != nil || len(tags) == 0 || tags[0] != ',' {
+		return Message{}, ErrMalformed
+	}
+	for _, tag := range tags[1:] {
+		var arg any
+		switch tag {
+		case 'i', 'f', 'c', 'r', 'm':
+			if len(data) < 4 {
+				return Message{}, ErrMalformed
+			}
+			v := binary.BigEndian.Uint32(data)
+			data = data[4:]
+			if tag == 'f' {
+				arg = math.Float32frombits(v)
+			} else {
+				arg = int32(v)
+			}
+		case 'h', 'd', 't':
+			if len(data) < 8 {
+				return Message{}, ErrMalformed
+			}
+			v := binary.BigEndian.Uint64(data)
+			data = data[8:]
+			if tag == 'd' {
+				arg = math.Float64frombits(v)
+			} else {
+				arg = int64(v)
+			}
+		case 's', 'S':
+			arg, data, err = readString(data)
+			if err != nil {
+				return Message{}, err
+			}
+		case 'b':
+			if len(data) < 4 {
+				return Message{}, ErrMalformed
+			}
+			n := int(binary.BigEndian.Uint32(data))
+			data = data[4:]
+			if n < 0 || pad(n) > len(data) {
+				return Message{}, ErrMalformed
+			}
+			arg, data = data[:n], data[pad(n):]
+		case 'T', 'F':
+			arg = tag == 'T'
+		case 'N', 'I':
+		default:
+			return Message{}, fmt.Errorf("%w: unsupported type tag %q", ErrMalformed, tag)
+		}
+		msg.Args = append(msg.Args, arg)
+	}
+	return msg, nil
+}
+
+// readString reads a null-terminated string padded to 4 bytes.
+func readString(data []byte) (string, []byte, error) {
+	n := bytes.IndexByte(data, 0)
+	if n < 0 || pad(n+1) > len(data) {
+		return "", nil, ErrMalformed
+	}
+	return string(data[:n]), data[pad(n+1):], nil
+}
+
+// pad rounds n up to a multiple of 4.
+func pad(n int) int {
+	return (n + 3) &^ 3
+}
+
+// Float returns arg as a parameter value: numbers as is, true as 1 and
+// false as 0. It reports false for any other argument.
+func Float(arg any) (float64, bool) {
+	switch v := arg.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
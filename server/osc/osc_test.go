@@ -0,0 +1,128 @@
+package osc
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+)
+
+// oscString encodes s null-terminated and padded to 4 bytes.
+func oscString(s string) []byte {
+	b := append([]byte(s), 0)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// message encodes an OSC message with float32, int32 and bool arguments.
+func message(address string, args ...any) []byte {
+	tags := ","
+	var data []byte
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case float32:
+			tags += "f"
+			data = binary.BigEndian.AppendUint32(data, math.Float32bits(v))
+		case int32:
+			tags += "i"
+			data = binary.BigEndian.AppendUint32(data, uint32(v))
+		case string:
+			tags += "s"
+			data = append(data, oscString(v)...)
+		case bool:
+			if v {
+				tags += "T"
+			} else {
+				tags += "F"
+			}
+		}
+	}
+	out := append(oscString(address), oscString(tags)...)
+	return append(out, data...)
+}
+
+// bundle encodes an OSC bundle of elements with an immediate time tag.
+func bundle(elements ...[]byte) []byte {
+	out := append(oscString(bundleTag), 0, 0, 0, 0, 0, 0, 0, 1)
+	for _, e := range elements {
+		out = binary.BigEndian.AppendUint32(out, uint32(len(e)))
+		out = append(out, e...)
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	msgs, err := Parse(message("/penumbra/par_front/Dimmer", float32(0.5), int32(3), "x", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Address != "/penumbra/par_front/Dimmer" {
+		t.Fatalf("Parse() = %+v", msgs)
+	}
+	want := []any{float32(0.5), int32(3), "x", true}
+	for i, arg := range msgs[0].Args {
+		if arg != want[i] {
+			t.Errorf("arg %d = %#v, want %#v", i, arg, want[i])
+		}
+	}
+
+	msgs, err = Parse(bundle(message("/a", float32(1)), bundle(message("/b"), message("/c", false))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 3 || msgs[0].Address != "/a" || msgs[1].Address != "/b" || msgs[2].Address != "/c" {
+		t.Errorf("nested bundle: %+v", msgs)
+	}
+
+	for name, data := range map[string][]byte{
+		"no address":     oscString(",f"),
+		"short argument": message("/a", float32(1))[:10],
+		"short bundle":   bundle(message("/a"))[:20],
+	} {
+		if _, err := Parse(data); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: got %v, want ErrMalformed", name, err)
+		}
+	}
+}
+
+func TestReceiver_Handle(t *testing.T) {
+	r := NewReceiver(config.OSCConfig{SessionID: "td"}, nil)
+	clock := time.UnixMilli(10_000)
+	r.now = func() time.Time { return clock }
+	src := "10.0.0.9:9000"
+
+	pkt, ok := r.handle(src, message("/penumbra/par_front/Dimmer", float32(0.75)))
+	if !ok || pkt.SessionID != "td" || pkt.Source != src || pkt.State["par_front/Dimmer"] != 0.75 {
+		t.Fatalf("message: %+v %v", pkt, ok)
+	}
+
+	// A bundle sets every parameter in it at once, keeping the earlier ones.
+	pkt, ok = r.handle(src, bundle(
+		message("/penumbra/par_front/Red", float32(1)),
+		message("/penumbra/par_front/Strobe", false),
+		message("/other/thing", float32(1)),
+		message("/penumbra/par_front/Mode", "fast"),
+	))
+	if !ok || len(pkt.State) != 3 || pkt.State["par_front/Dimmer"] != 0.75 || pkt.State["par_front/Red"] != 1 {
+		t.Fatalf("bundle: %+v %v", pkt, ok)
+	}
+	first := pkt.Ts
+
+	if _, ok := r.handle(src, message("/other/thing", float32(1))); ok {
+		t.Errorf("message outside the prefix produced a packet")
+	}
+	pkt, _ = r.handle(src, message("/penumbra/par_front/Dimmer", int32(0)))
+	if pkt.Ts <= first {
+		t.Errorf("ts %d not after %d on the same clock", pkt.Ts, first)
+	}
+
+	want := Stats{Messages: 7, Bundles: 1, Ignored: 3}
+	if got := r.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package osc
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"maps"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/udp"
+)
+
+// Stats counts the OSC input's traffic.
+type Stats struct {
+	Messages uint64 `json:"messages"`
+	Bundles  uint64 `json:"bundles"`
+	Ignored  uint64 `json:"ignored"` // outside the prefix, or without a value
+	Errors   uint64 `json:"errors"`  // malformed datagrams
+}
+
+// sender is the state built up from one OSC sender's messages.
+type sender struct {
+	state  map[string]float64
+	lastTs int64
+}
+
+// Receiver listens for OSC on cfg.Port. Each sender's messages build up a
+// full parameter state, which is handed to handler as a udp.StatePacket
+// once per datagram, so OSC input takes the same path as msgpack emitters.
+type Receiver struct {
+	cfg     config.OSCConfig
+	handler func(udp.StatePacket)
+	now     func() time.Time
+
+	mu      sync.Mutex
+	senders map[string]*sender // keyed by source address
+	stats   Stats
+}
+
+// NewReceiver creates a Receiver that calls handler with each sender's
+// state after every datagram that changes it.
+func NewReceiver(cfg config.OSCConfig, handler func(udp.StatePacket)) *Receiver {
+	return &Receiver{
+		cfg:     cfg,
+		handler: handler,
+		now:     time.Now,
+		senders: make(map[string]*sender),
+	}
+}
+
+// Stats returns the receiver's counters.
+func (r *Receiver) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Listen opens the OSC socket and blocks, calling the handler for every
+// datagram that sets a parameter. Logs and continues on decode errors;
+// fatals on socket errors.
+func (r *Receiver) Listen() {
+	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("0.0.0.0:%d", r.cfg.Port))
+	if err != nil {
+		log.Fatalf("osc: resolve addr: %v", err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		log.Fatalf("osc: listen: %v", err)
+	}
+	defer conn.Close()
+	log.Printf("osc: listening on :%d", r.cfg.Port)
+
+	buf := make([]byte, 65536)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			log.Printf("osc: read error: %v", err)
+			continue
+		}
+		if pkt, ok := r.handle(from.String(), buf[:n]); ok {
+			r.handler(pkt)
+		}
+	}
+}
+
+// handle applies a datagram from source to its sender's state and returns
+// that state as a packet, or false if the datagram set no parameter. A
+// message sets the parameter named by its address below the prefix to its
+// first argument.
+func (r *Receiver) handle(source string, data []byte) (udp.StatePacket, bool) {
+	msgs, err := Parse(data)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.stats.Errors++
+		log.Printf("osc: %s: %v", source, err)
+		return udp.StatePacket{}, false
+	}
+	if bytes.HasPrefix(data, []byte(bundleTag)) {
+		r.stats.Bundles++
+	}
+
+	s := r.senders[source]
+	if s == nil {
+		s = &sender{state: make(map[string]float64)}
+		r.senders[source] = s
+	}
+	prefix := r.cfg.AddressPrefix() + "/"
+	set := false
+	for _, msg := range msgs {
+		r.stats.Messages++
+		param, ok := strings.CutPrefix(msg.Address, prefix)
+		if !ok || param == "" || len(msg.Args) == 0 {
+			r.stats.Ignored++
+			continue
+		}
+		v, ok := Float(msg.Args[0])
+		if !ok {
+			r.stats.Ignored++
+			continue
+		}
+		s.state[param] = v
+		set = true
+	}
+	if !set {
+		return udp.StatePacket{}, false
+	}
+
+	// OSC carries no usable clock, so packets are stamped on arrival, kept
+	// strictly increasing for playback's ordering.
+	s.lastTs = max(r.now().UnixMilli(), s.lastTs+1)
+	return udp.StatePacket{
+		SessionID: r.cfg.Session(),
+		Ts:        s.lastTs,
+		State:     maps.Clone(s.state),
+		Source:    source,
+	}, true
+}
@@ -0,0 +1,199 @@
+package output
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/dmx"
+)
+
+// MasterLevels is a snapshot of the master levels, each 0–1.
+type MasterLevels struct {
+	Grand      float64            `json:"grand"`
+	Submasters map[string]float64 `json:"submasters"`
+}
+
+// Masters scales intensity at output time: the grand master scales the
+// intensity channels of every patch, and each sub-master in cfg.Submasters
+// scales its members on top. Emitter state is untouched. Add it as the first
+// processor so only Penumbra's own output is scaled. Levels are runtime-only
+// and start at full.
+type Masters struct {
+	cfg      *config.Config
+	resolve  config.FixtureResolver
+	onChange func()
+
+	mu         sync.Mutex
+	grand      float64
+	submasters map[string]float64 // only levels that have been set
+}
+
+// NewMasters creates masters at full. onChange is called after every level
+// change, typically to reprocess every universe.
+func NewMasters(cfg *config.Config, resolve config.FixtureResolver, onChange func()) *Masters {
+	return &Masters{
+		cfg:        cfg,
+		resolve:    resolve,
+		onChange:   onChange,
+		grand:      1,
+		submasters: make(map[string]float64),
+	}
+}
+
+// Validate checks a level requested for the named sub-master, or the grand
+// master if name is empty: the sub-master must exist and the level be 0–1.
+// Callers taking levels from clients check them here rather than rely on the
+// setters' clamping.
+func (m *Masters) Validate(name string, level float64) error {
+	inRange := level >= 0 && level <= 1
+	if name == "" {
+		if !inRange {
+			return fmt.Errorf("grand must be 0-1")
+		}
+		return nil
+	}
+	if _, ok := m.cfg.Submasters[name]; !ok {
+		return fmt.Errorf("unknown submaster %q", name)
+	}
+	if !inRange {
+		return fmt.Errorf("submaster %q: level must be 0-1", name)
+	}
+	return nil
+}
+
+// SetGrand sets the grand master level, clamped to 0–1.
+func (m *Masters) SetGrand(level float64) {
+	m.mu.Lock()
+	m.grand = clamp(level)
+	m.mu.Unlock()
+	m.changed()
+}
+
+// SetSubmaster sets the level of the named sub-master, clamped to 0–1.
+func (m *Masters) SetSubmaster(name string, level float64) error {
+	if _, ok := m.cfg.Submasters[name]; !ok {
+		return fmt.Errorf("unknown submaster %q", name)
+	}
+	m.mu.Lock()
+	m.submasters[name] = clamp(level)
+	m.mu.Unlock()
+	m.changed()
+	return nil
+}
+
+// Levels returns the grand master and every configured sub-master level.
+func (m *Masters) Levels() MasterLevels {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	levels := MasterLevels{Grand: m.grand, Submasters: make(map[string]float64, len(m.cfg.Submasters))}
+	for name := range m.cfg.Submasters {
+		levels.Submasters[name] = m.level(name)
+	}
+	return levels
+}
+
+// Process scales the intensity channels of universe by their master levels.
+func (m *Masters) Process(universe int, u config.UniverseConfig, data []byte) []byte {
+	m.mu.Lock()
+	scale := m.scale(universe, u)
+	m.mu.Unlock()
+	if len(scale) == 0 {
+		return data
+	}
+	out := make([]byte, len(data))
+	copy(out, data)
+	for ch, s := range scale {
+		c, f := ch.Channel-1, ch.Fine-1
+		if c < 0 || c >= len(out) || c >= dmx.UniverseSize {
+			continue
+		}
+		if f < 0 || f >= len(out) {
+			out[c] = byte(math.Round(float64(out[c]) * s))
+			continue
+		}
+		v := uint16(math.Round(float64(uint16(out[c])<<8|uint16(out[f])) * s))
+		out[c], out[f] = byte(v>>8), byte(v)
+	}
+	return out
+}
+
+// scale returns the factor for every channel of universe that is not at
+// full. Must be called with m.mu held.
+func (m *Masters) scale(universe int, u config.UniverseConfig) map[config.Intensity]float64 {
+	if m.atFull() {
+		return nil
+	}
+	scale := make(map[config.Intensity]float64)
+	apply := func(ch config.Intensity, level float64) {
+		if level == 1 {
+			return
+		}
+		if s, ok := scale[ch]; ok {
+			level *= s
+		}
+		scale[ch] = level
+	}
+	for _, p := range u.Patches {
+		level := m.grand
+		for name, sm := range m.cfg.Submasters {
+			if slices.Contains(sm.Patches, p.Label) {
+				level *= m.level(name)
+			}
+		}
+		for _, ch := range p.IntensityChannels(m.resolve) {
+			apply(ch, level)
+		}
+	}
+	for name, sm := range m.cfg.Submasters {
+		level := m.level(name)
+		for _, param := range sm.Parameters {
+			for _, t := range m.cfg.Parameters[param] {
+				if t.Universe != universe {
+					continue
+				}
+				ch := config.Intensity{Channel: t.Channel}
+				if t.Is16Bit() {
+					ch.Fine = t.Fine()
+				}
+				apply(ch, level)
+			}
+		}
+	}
+	return scale
+}
+
+// atFull reports whether every master is at full. Must be called with m.mu
+// held.
+func (m *Masters) atFull() bool {
+	if m.grand != 1 {
+		return false
+	}
+	for _, level := range m.submasters {
+		if level != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// level returns the level of the named sub-master. Must be called with m.mu
+// held.
+func (m *Masters) level(name string) float64 {
+	if level, ok := m.submasters[name]; ok {
+		return level
+	}
+	return 1
+}
+
+func (m *Masters) changed() {
+	if m.onChange != nil {
+		m.onChange()
+	}
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
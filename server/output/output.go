@@ -0,0 +1,545 @@
+// Package output turns parameter state into per-universe DMX frames and hands
+// them to output drivers (E1.31, Art-Net, ...). Drivers register themselves by
+// name from an init function, like database/sql drivers, and are enabled in
+// config.json under output.drivers.
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/dmx"
+	"github.com/footgunz/penumbra/fixtures"
+)
+
+// Frame is one universe's computed DMX data.
+// Config is the universe's configuration at the time the frame was computed,
+// so drivers can still address a universe after it is removed from config.
+type Frame struct {
+	Universe int
+	Config   config.UniverseConfig
+	Data     []byte // dmx.UniverseSize channels
+}
+
+// Driver sends frames to devices. Send is called from the refresh loop with
+// every frame that is due this tick, or, for a Router, with the due frames
+// it handles. Driver calls are never concurrent, and run outside the
+// Manager's lock, so a driver may block on its device without holding up
+// Dispatch.
+type Driver interface {
+	Send(frames []Frame) error
+	Close() error
+}
+
+// Terminator is implemented by drivers whose protocol can tell receivers a
+// stream has ended (E1.31 Stream_Terminated). Terminate is called when
+// universes are removed, on blackout in terminate mode, and on shutdown,
+// with the frames the driver handles.
+type Terminator interface {
+	Terminate(frames []Frame) error
+}
+
+// Router is implemented by drivers that only send some universes, e.g. the
+// E1.31 driver sends the universes whose protocol is "e131". Handles reports
+// whether the driver sends u. A driver that doesn't implement Router is
+// given every frame, e.g. to capture all output whatever its protocol.
+type Router interface {
+	Handles(u config.UniverseConfig) bool
+}
+
+// Processor adjusts a universe's rendered frame before it is buffered, e.g.
+// to merge in external sACN sources. Process returns the frame to send and
+// must not modify data.
+type Processor interface {
+	Process(universe int, u config.UniverseConfig, data []byte) []byte
+}
+
+// Factory creates a driver from the server config. resolve looks up fixture
+// channel counts for drivers that lay out data by patch.
+type Factory func(cfg *config.Config, resolve config.ChannelCountResolver) (Driver, error)
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a driver available under name. It panics if name is
+// registered twice; call it from the driver package's init function.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("output: Register called twice for driver " + name)
+	}
+	registry[name] = factory
+}
+
+// Registered returns the sorted names of all registered drivers.
+func Registered() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats reports per-driver activity for the status message.
+type Stats struct {
+	Name        string `json:"name"`
+	Frames      uint64 `json:"frames"`        // frames handed to Send
+	Errors      uint64 `json:"errors"`        // Send/Terminate calls that failed
+	LastError   string `json:"last_error"`    // most recent error, "" if none
+	LastErrorAt int64  `json:"last_error_at"` // unix ms of LastError, 0 if none
+}
+
+type driverState struct {
+	name   string
+	driver Driver
+	stats  Stats
+}
+
+// frame is the last computed DMX data for one universe. rendered is the data
+// as rendered from parameter state, mixed is rendered after any crossfade,
+// and Frame.Data is mixed after processors.
+type frame struct {
+	Frame
+	rendered   []byte
+	mixed      []byte
+	lastChange time.Time
+	lastSent   time.Time
+}
+
+// crossfade blends every universe from its output at start to whatever is
+// rendered since, over dur.
+type crossfade struct {
+	from  map[int][]byte
+	start time.Time
+	dur   time.Duration
+}
+
+// progress returns how far the fade has run at now, 0–1.
+func (c *crossfade) progress(now time.Time) float64 {
+	return min(1, float64(now.Sub(c.start))/float64(c.dur))
+}
+
+// Manager owns the per-universe frame buffers and the refresh loop.
+//
+// Dispatch only updates the buffers; Run hands every buffered frame to each
+// driver at cfg.Output.RefreshHz while it is changing and every
+// cfg.Output.KeepAliveMs once it is static, independent of how often the
+// emitter sends.
+type Manager struct {
+	cfg   *config.Config
+	store *fixtures.Store
+
+	// sendMu serializes driver calls and is taken before mu, so frames
+	// reach the drivers in the order they were taken from the buffers.
+	sendMu sync.Mutex
+
+	mu         sync.Mutex
+	drivers    []*driverState
+	processors []Processor
+	frames     map[int]*frame
+	ended      []Frame      // streams terminated since the drivers were last called
+	terminated map[int]bool // ended by TerminateAll, until dispatched again
+	fade       *crossfade   // nil when no crossfade is running
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewManager creates the drivers listed in cfg.Output.Drivers.
+// Call Run() in a goroutine to start sending.
+func NewManager(cfg *config.Config, store *fixtures.Store) (*Manager, error) {
+	m := &Manager{
+		cfg:        cfg,
+		store:      store,
+		frames:     make(map[int]*frame),
+		terminated: make(map[int]bool),
+		done:       make(chan struct{}),
+	}
+	for _, name := range cfg.Output.Drivers {
+		registryMu.Lock()
+		factory, ok := registry[name]
+		registryMu.Unlock()
+		if !ok {
+			m.closeDrivers()
+			return nil, fmt.Errorf("output: unknown driver %q (registered: %v)", name, Registered())
+		}
+		d, err := factory(cfg, store.ChannelCount)
+		if err != nil {
+			m.closeDrivers()
+			return nil, fmt.Errorf("output: driver %q: %w", name, err)
+		}
+		m.drivers = append(m.drivers, &driverState{name: name, driver: d, stats: Stats{Name: name}})
+	}
+	return m, nil
+}
+
+// Dispatch renders state into frames for every configured universe.
+// Frames go out on the next refresh tick.
+func (m *Manager) Dispatch(state map[string]float64, cfg *config.Config) {
+	m.dispatch(dmx.Render(state, cfg, m.store.Fixture), cfg)
+}
+
+// DispatchBlackout sends every mapped channel to 0, bypassing target
+// transforms; unmapped channels keep their defaults.
+func (m *Manager) DispatchBlackout(cfg *config.Config) {
+	m.dispatch(dmx.Blackout(cfg, m.store.Fixture), cfg)
+}
+
+// DispatchFrames replaces the rendered frames of the given universes with
+// raw DMX data, e.g. from a stored scene. Unconfigured universes are ignored
+// and the other universes keep their frames.
+func (m *Manager) DispatchFrames(frames map[int][]byte, cfg *config.Config) {
+	universes := make(map[int][]byte, len(frames))
+	for universe, data := range frames {
+		if _, ok := cfg.Universes[universe]; ok {
+			universes[universe] = data
+		}
+	}
+	m.dispatch(universes, cfg)
+}
+
+// Rendered returns a copy of every universe's last rendered frame, before
+// crossfades and processors.
+func (m *Manager) Rendered() map[int][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[int][]byte, len(m.frames))
+	for universe, f := range m.frames {
+		out[universe] = bytes.Clone(f.rendered)
+	}
+	return out
+}
+
+func (m *Manager) dispatch(universes map[int][]byte, cfg *config.Config) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for universe, data := range universes {
+		delete(m.terminated, universe)
+		m.update(universe, cfg.Universes[universe], data, now)
+	}
+}
+
+// Fade starts a crossfade: frames dispatched from now on blend in from the
+// current output over d, rather than replacing it. Universes with no current
+// output fade in from 0. A fade already running is taken over from wherever
+// it has got to; d <= 0 cancels it, so the next dispatch cuts.
+func (m *Manager) Fade(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d <= 0 {
+		m.fade = nil
+		return
+	}
+	from := make(map[int][]byte, len(m.frames))
+	for universe, f := range m.frames {
+		from[universe] = f.mixed
+	}
+	m.fade = &crossfade{from: from, start: time.Now(), dur: d}
+}
+
+// Fading reports whether a crossfade is running.
+func (m *Manager) Fading() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fade != nil
+}
+
+// AddProcessor appends p to the processors applied to every rendered frame.
+// Call before Run.
+func (m *Manager) AddProcessor(p Processor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.processors = append(m.processors, p)
+}
+
+// Reprocess re-applies the processors to the last rendered frame of each
+// given universe, for when a processor's input changes between dispatches.
+// A configured universe that has not been dispatched yet starts from an
+// all-zero frame; one ended by TerminateAll stays ended until the next
+// dispatch.
+func (m *Manager) Reprocess(universes []int) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, universe := range universes {
+		u, ok := m.cfg.Universes[universe]
+		if !ok || m.terminated[universe] {
+			continue
+		}
+		rendered := make([]byte, dmx.UniverseSize)
+		if f, ok := m.frames[universe]; ok {
+			rendered = f.rendered
+		}
+		m.update(universe, u, rendered, now)
+	}
+}
+
+// update must be called with m.mu held.
+func (m *Manager) update(universe int, u config.UniverseConfig, rendered []byte, now time.Time) {
+	mixed := rendered
+	if m.fade != nil {
+		if p := m.fade.progress(now); p < 1 {
+			from, ok := m.fade.from[universe]
+			if !ok {
+				from = make([]byte, dmx.UniverseSize)
+			}
+			mixed = dmx.Blend(from, rendered, p, m.cfg.UniverseTargets(universe))
+		}
+	}
+	data := mixed
+	for _, p := range m.processors {
+		data = p.Process(universe, u, data)
+	}
+
+	f, ok := m.frames[universe]
+	if ok && f.Config.OutputProtocol() != u.OutputProtocol() {
+		// Moving to another transport: end the old stream first.
+		m.terminate([]int{universe})
+		ok = false
+	}
+	if !ok {
+		m.frames[universe] = &frame{
+			Frame:      Frame{Universe: universe, Config: u, Data: data},
+			rendered:   rendered,
+			mixed:      mixed,
+			lastChange: now,
+		}
+		return
+	}
+	f.Config = u
+	f.rendered = rendered
+	f.mixed = mixed
+	if !bytes.Equal(f.Data, data) {
+		f.Data = data
+		f.lastChange = now
+	}
+}
+
+// Run retransmits buffered frames on a fixed ticker. Blocks until Close.
+func (m *Manager) Run() {
+	ticker := time.NewTicker(time.Second / time.Duration(m.cfg.Output.RefreshHz))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			m.refresh(now, false)
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// Flush sends every buffered frame immediately, whether or not it is due.
+func (m *Manager) Flush() {
+	m.refresh(time.Now(), true)
+}
+
+// Prune terminates every universe that is no longer in cfg.Universes.
+// Call after the universe list changes.
+func (m *Manager) Prune(cfg *config.Config) {
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+	m.mu.Lock()
+	var removed []int
+	for universe := range m.frames {
+		if _, ok := cfg.Universes[universe]; !ok {
+			log.Printf("output: universe %d removed, terminating stream", universe)
+			removed = append(removed, universe)
+		}
+	}
+	m.terminate(removed)
+	ended := m.takeEnded()
+	m.mu.Unlock()
+	m.deliver(ended, nil)
+}
+
+// TerminateAll ends every active stream. Drivers that support it tell
+// receivers the source went away; the others simply stop sending.
+// A later Dispatch restarts output; Reprocess does not.
+func (m *Manager) TerminateAll() {
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+	m.mu.Lock()
+	universes := m.universes()
+	for _, universe := range universes {
+		m.terminated[universe] = true
+	}
+	m.terminate(universes)
+	ended := m.takeEnded()
+	m.mu.Unlock()
+	m.deliver(ended, nil)
+}
+
+// Close terminates all streams, stops Run and closes every driver.
+// Safe to call more than once.
+func (m *Manager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+		m.sendMu.Lock()
+		defer m.sendMu.Unlock()
+		m.mu.Lock()
+		m.terminate(m.universes())
+		ended := m.takeEnded()
+		m.mu.Unlock()
+		m.deliver(ended, nil)
+		m.closeDrivers()
+	})
+}
+
+// Stats returns a snapshot of every driver's counters, in config order.
+func (m *Manager) Stats() []Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Stats, len(m.drivers))
+	for i, d := range m.drivers {
+		out[i] = d.stats
+	}
+	return out
+}
+
+// refresh hands every frame that is due at now (or every frame, if force)
+// to each driver, after ending any streams terminated since the last call.
+func (m *Manager) refresh(now time.Time, force bool) {
+	keepAlive := time.Duration(m.cfg.Output.KeepAliveMs) * time.Millisecond
+
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+	m.mu.Lock()
+	if m.fade != nil {
+		// Advance the crossfade; it ends once every frame has reached its
+		// rendered data.
+		for universe, f := range m.frames {
+			m.update(universe, f.Config, f.rendered, now)
+		}
+		if m.fade.progress(now) >= 1 {
+			m.fade = nil
+		}
+	}
+	var due []Frame
+	for _, f := range m.frames {
+		if force || f.due(now, keepAlive) {
+			due = append(due, f.Frame)
+			f.lastSent = now
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Universe < due[j].Universe })
+	ended := m.takeEnded()
+	m.mu.Unlock()
+	m.deliver(ended, due)
+}
+
+// deliver hands the frames of ended streams to each Terminator, then the due
+// frames to each driver's Send, giving a Router only the frames it handles.
+// It runs without m.mu, so a driver blocked on its device doesn't hold up
+// Dispatch; m.sendMu must be held.
+func (m *Manager) deliver(ended, due []Frame) {
+	if len(ended) == 0 && len(due) == 0 {
+		return
+	}
+	sent := make([]int, len(m.drivers))
+	errs := make([][]error, len(m.drivers))
+	for i, d := range m.drivers {
+		if t, ok := d.driver.(Terminator); ok {
+			if frames := d.handled(ended); len(frames) > 0 {
+				errs[i] = append(errs[i], t.Terminate(frames))
+			}
+		}
+		if frames := d.handled(due); len(frames) > 0 {
+			sent[i] = len(frames)
+			errs[i] = append(errs[i], d.driver.Send(frames))
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, d := range m.drivers {
+		d.stats.Frames += uint64(sent[i])
+		for _, err := range errs[i] {
+			d.record(err)
+		}
+	}
+}
+
+// due reports whether f should be sent at now: on every tick while the frame
+// changed within the last keepAlive, otherwise once per keepAlive.
+func (f *frame) due(now time.Time, keepAlive time.Duration) bool {
+	if now.Sub(f.lastChange) < keepAlive {
+		return true
+	}
+	return now.Sub(f.lastSent) >= keepAlive
+}
+
+// terminate drops the given universes' frames and queues them for the
+// drivers' Terminate, which the next deliver calls. Must be called with m.mu
+// held.
+func (m *Manager) terminate(universes []int) {
+	sort.Ints(universes)
+	for _, universe := range universes {
+		if f, ok := m.frames[universe]; ok {
+			m.ended = append(m.ended, f.Frame)
+			delete(m.frames, universe)
+		}
+	}
+}
+
+// takeEnded returns the queued ended streams and clears the queue. Must be
+// called with m.mu held.
+func (m *Manager) takeEnded() []Frame {
+	ended := m.ended
+	m.ended = nil
+	return ended
+}
+
+// universes must be called with m.mu held.
+func (m *Manager) universes() []int {
+	ids := make([]int, 0, len(m.frames))
+	for universe := range m.frames {
+		ids = append(ids, universe)
+	}
+	return ids
+}
+
+func (m *Manager) closeDrivers() {
+	for _, d := range m.drivers {
+		if err := d.driver.Close(); err != nil {
+			log.Printf("output: close %s: %v", d.name, err)
+		}
+	}
+}
+
+// handled returns the frames d sends: those its Handles accepts if it is a
+// Router, otherwise all of them.
+func (d *driverState) handled(frames []Frame) []Frame {
+	r, ok := d.driver.(Router)
+	if !ok {
+		return frames
+	}
+	var out []Frame
+	for _, f := range frames {
+		if r.Handles(f.Config) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (d *driverState) record(err error) {
+	if err == nil {
+		return
+	}
+	d.stats.Errors++
+	d.stats.LastError = err.Error()
+	d.stats.LastErrorAt = time.Now().UnixMilli()
+}
@@ -0,0 +1,352 @@
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/fixtures"
+)
+
+// fakeDriver records what the manager hands it.
+type fakeDriver struct {
+	sent       [][]Frame
+	terminated []Frame
+	sendErr    error
+}
+
+func (f *fakeDriver) Send(frames []Frame) error {
+	f.sent = append(f.sent, frames)
+	return f.sendErr
+}
+
+func (f *fakeDriver) Terminate(frames []Frame) error {
+	f.terminated = append(f.terminated, frames...)
+	return nil
+}
+
+func (f *fakeDriver) Close() error { return nil }
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Universes: map[int]config.UniverseConfig{1: {Label: "one"}, 2: {Label: "two"}},
+		Parameters: map[string]config.ParameterConfig{
+			"a": {{Universe: 1, Channel: 1}},
+			"b": {{Universe: 2, Channel: 3}},
+		},
+		Output: config.OutputConfig{Drivers: []string{"fake"}, RefreshHz: 44, KeepAliveMs: 1000},
+	}
+}
+
+func TestManager_DispatchFlushTerminate(t *testing.T) {
+	fake := &fakeDriver{sendErr: errors.New("boom")}
+	Register("fake", func(*config.Config, config.ChannelCountResolver) (Driver, error) { return fake, nil })
+
+	cfg := testConfig()
+	m, err := NewManager(cfg, fixtures.NewStore())
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	m.Dispatch(map[string]float64{"a": 1, "b": 0.5}, cfg)
+	m.Flush()
+
+	if len(fake.sent) != 1 || len(fake.sent[0]) != 2 {
+		t.Fatalf("expected one Send with 2 frames, got %v", fake.sent)
+	}
+	first := fake.sent[0][0]
+	if first.Universe != 1 || first.Data[0] != 255 || first.Config.Label != "one" {
+		t.Fatalf("unexpected first frame: universe=%d ch1=%d", first.Universe, first.Data[0])
+	}
+	if fake.sent[0][1].Data[2] != 128 {
+		t.Fatalf("universe 2 ch3 = %d, want 128", fake.sent[0][1].Data[2])
+	}
+
+	stats := m.Stats()
+	if len(stats) != 1 || stats[0].Frames != 2 || stats[0].Errors != 1 || stats[0].LastError != "boom" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	delete(cfg.Universes, 2)
+	m.Prune(cfg)
+	if len(fake.terminated) != 1 || fake.terminated[0].Universe != 2 {
+		t.Fatalf("expected universe 2 terminated, got %v", fake.terminated)
+	}
+
+	m.Close()
+	if len(fake.terminated) != 2 || fake.terminated[1].Universe != 1 {
+		t.Fatalf("expected universe 1 terminated on close, got %v", fake.terminated)
+	}
+}
+
+func TestNewManager_UnknownDriver(t *testing.T) {
+	cfg := testConfig()
+	cfg.Output.Drivers = []string{"does-not-exist"}
+	if _, err := NewManager(cfg, fixtures.NewStore()); err == nil {
+		t.Fatal("expected unknown driver error")
+	}
+}
+
+func TestFrameDue(t *testing.T) {
+	keepAlive := time.Second
+	now := time.Now()
+
+	changing := &frame{lastChange: now.Add(-100 * time.Millisecond), lastSent: now.Add(-20 * time.Millisecond)}
+	if !changing.due(now, keepAlive) {
+		t.Error("recently changed frame should be sent every tick")
+	}
+
+	static := &frame{lastChange: now.Add(-5 * time.Second), lastSent: now.Add(-200 * time.Millisecond)}
+	if static.due(now, keepAlive) {
+		t.Error("static frame should wait for the keep-alive interval")
+	}
+
+	stale := &frame{lastChange: now.Add(-5 * time.Second), lastSent: now.Add(-time.Second)}
+	if !stale.due(now, keepAlive) {
+		t.Error("static frame should be resent once the keep-alive interval elapses")
+	}
+}
+
+func TestParking(t *testing.T) {
+	var changed []int
+	p := NewParking(func(universes []int) { changed = append(changed, universes...) })
+	data := make([]byte, 512)
+	data[6] = 99
+
+	p.Park(1, 7, 10)
+	p.Park(1, 8, 255)
+	out := p.Process(1, config.UniverseConfig{}, data)
+	if out[6] != 10 || out[7] != 255 || data[6] != 99 {
+		t.Fatalf("process = %d,%d (input %d), want 10,255 and input untouched", out[6], out[7], data[6])
+	}
+	if got := p.Process(2, config.UniverseConfig{}, data); got[6] != 99 {
+		t.Fatalf("other universe modified")
+	}
+
+	if !p.Unpark(1, 7) || p.Unpark(1, 7) {
+		t.Fatalf("unpark should succeed once")
+	}
+	if got := p.Parked(); len(got) != 1 || got[0] != (ParkedChannel{Universe: 1, Channel: 8, Value: 255}) {
+		t.Fatalf("parked = %+v", got)
+	}
+	p.UnparkAll()
+	if len(p.Parked()) != 0 || len(changed) != 4 {
+		t.Fatalf("after UnparkAll: parked %v, %d change notifications", p.Parked(), len(changed))
+	}
+}
+
+func TestManager_Fade(t *testing.T) {
+	cfg := testConfig()
+	cfg.Output.Drivers = nil
+	m, err := NewManager(cfg, fixtures.NewStore())
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	m.Dispatch(map[string]float64{"a": 1}, cfg)
+	m.Fade(time.Second)
+	start := m.fade.start
+	m.Dispatch(map[string]float64{"a": 0}, cfg)
+
+	m.refresh(start.Add(500*time.Millisecond), false)
+	if got := m.frames[1].Data[0]; got != 128 {
+		t.Fatalf("halfway through fade: ch1 = %d, want 128", got)
+	}
+
+	m.refresh(start.Add(time.Second), false)
+	if got := m.frames[1].Data[0]; got != 0 || m.Fading() {
+		t.Fatalf("after fade: ch1 = %d, fading %v; want 0, false", got, m.Fading())
+	}
+
+	// Fade(0) cuts: the next dispatch lands immediately.
+	m.Fade(time.Second)
+	m.Fade(0)
+	m.Dispatch(map[string]float64{"a": 1}, cfg)
+	if got := m.frames[1].Data[0]; got != 255 {
+		t.Fatalf("after cut: ch1 = %d, want 255", got)
+	}
+}
+
+func TestMasters(t *testing.T) {
+	cfg := &config.Config{
+		Universes: map[int]config.UniverseConfig{1: {Patches: []config.Patch{
+			{FixtureKey: "manual", Label: "Par", StartAddress: 1, Channels: []string{"Dimmer", "Red", "Strobe"}},
+			{FixtureKey: "manual", Label: "Strip", StartAddress: 4, Channels: []string{"Red", "Green"}},
+		}}},
+		Parameters: map[string]config.ParameterConfig{
+			"haze": {{Universe: 1, Channel: 10}},
+		},
+		Submasters: map[string]config.Submaster{
+			"strip": {Patches: []string{"Strip"}},
+			"fx":    {Parameters: []string{"haze"}},
+		},
+	}
+	changes := 0
+	m := NewMasters(cfg, nil, func() { changes++ })
+	u := cfg.Universes[1]
+	data := make([]byte, 512)
+	for i := range data {
+		data[i] = 200
+	}
+
+	if got := m.Process(1, u, data); &got[0] != &data[0] {
+		t.Fatal("masters at full should pass data through")
+	}
+
+	m.SetGrand(0.5)
+	if err := m.SetSubmaster("strip", 0.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSubmaster("fx", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetSubmaster("nope", 1); err == nil {
+		t.Fatal("expected unknown submaster error")
+	}
+	got := m.Process(1, u, data)
+	// Par: only its dimmer is scaled. Strip has no dimmer, so its colours
+	// are, by both the grand master and its submaster. haze is in fx.
+	want := map[int]byte{0: 100, 1: 200, 2: 200, 3: 50, 4: 50, 9: 0, 10: 200}
+	for i, v := range want {
+		if got[i] != v {
+			t.Errorf("channel %d = %d, want %d", i+1, got[i], v)
+		}
+	}
+	if data[0] != 200 {
+		t.Error("Process modified its input")
+	}
+	if changes != 3 {
+		t.Errorf("onChange called %d times, want 3", changes)
+	}
+	if l := m.Levels(); l.Grand != 0.5 || l.Submasters["strip"] != 0.5 || l.Submasters["fx"] != 0 {
+		t.Errorf("levels = %+v", l)
+	}
+
+	if err := m.Validate("strip", 1); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	for name, level := range map[string]float64{"": 1.5, "strip": -0.1, "nope": 0.5} {
+		if err := m.Validate(name, level); err == nil {
+			t.Errorf("Validate(%q, %g) accepted", name, level)
+		}
+	}
+}
+
+func TestManager_TerminateAllHoldsUntilDispatch(t *testing.T) {
+	fake := &fakeDriver{}
+	Register("fake-terminate", func(*config.Config, config.ChannelCountResolver) (Driver, error) { return fake, nil })
+
+	cfg := testConfig()
+	cfg.Output.Drivers = []string{"fake-terminate"}
+	m, err := NewManager(cfg, fixtures.NewStore())
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	m.Dispatch(map[string]float64{"a": 1, "b": 1}, cfg)
+	m.TerminateAll()
+
+	// A processor's input changing during blackout mustn't restart the
+	// terminated streams.
+	m.Reprocess([]int{1, 2})
+	if len(m.frames) != 0 {
+		t.Fatalf("Reprocess revived %d universes", len(m.frames))
+	}
+	m.Flush()
+	if len(fake.sent) != 0 {
+		t.Fatalf("sent %v after TerminateAll", fake.sent)
+	}
+
+	m.Dispatch(map[string]float64{"a": 1}, cfg)
+	m.Reprocess([]int{1, 2})
+	m.Flush()
+	if len(fake.sent) != 1 || len(fake.sent[0]) != 2 {
+		t.Fatalf("sent %v, want both universes after the next dispatch", fake.sent)
+	}
+}
+
+// routedDriver is a fakeDriver that only handles one protocol.
+type routedDriver struct {
+	fakeDriver
+	protocol string
+}
+
+func (r *routedDriver) Handles(u config.UniverseConfig) bool { return u.Protocol == r.protocol }
+
+func TestManager_Routing(t *testing.T) {
+	capture, art := &fakeDriver{}, &routedDriver{protocol: "art"}
+	Register("fake-capture", func(*config.Config, config.ChannelCountResolver) (Driver, error) { return capture, nil })
+	Register("fake-art", func(*config.Config, config.ChannelCountResolver) (Driver, error) { return art, nil })
+
+	cfg := testConfig()
+	cfg.Output.Drivers = []string{"fake-capture", "fake-art"}
+	cfg.Universes[2] = config.UniverseConfig{Label: "two", Protocol: "art"}
+	m, err := NewManager(cfg, fixtures.NewStore())
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	m.Dispatch(map[string]float64{"a": 1, "b": 1}, cfg)
+	m.Flush()
+	if len(capture.sent) != 1 || len(capture.sent[0]) != 2 {
+		t.Fatalf("capture got %v, want both universes", capture.sent)
+	}
+	if len(art.sent) != 1 || len(art.sent[0]) != 1 || art.sent[0][0].Universe != 2 {
+		t.Fatalf("art got %v, want universe 2 only", art.sent)
+	}
+	if stats := m.Stats(); stats[0].Frames != 2 || stats[1].Frames != 1 {
+		t.Fatalf("stats = %+v, want 2 and 1 frames", stats)
+	}
+
+	// Moving universe 2 to another protocol ends its art stream on the next
+	// refresh.
+	cfg.Universes[2] = config.UniverseConfig{Label: "two"}
+	m.Dispatch(map[string]float64{"a": 1, "b": 1}, cfg)
+	m.Flush()
+	if len(art.terminated) != 1 || art.terminated[0].Universe != 2 || len(art.sent) != 1 {
+		t.Fatalf("art terminated %v sent %v, want universe 2 ended", art.terminated, art.sent)
+	}
+	if len(capture.terminated) != 1 || len(capture.sent[1]) != 2 {
+		t.Fatalf("capture terminated %v sent %v", capture.terminated, capture.sent)
+	}
+}
+
+// blockingDriver blocks in Send until release is closed.
+type blockingDriver struct {
+	sending chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingDriver) Send([]Frame) error {
+	close(b.sending)
+	<-b.release
+	return nil
+}
+
+func (b *blockingDriver) Close() error { return nil }
+
+func TestManager_SendDoesNotBlockDispatch(t *testing.T) {
+	slow := &blockingDriver{sending: make(chan struct{}), release: make(chan struct{})}
+	Register("fake-blocking", func(*config.Config, config.ChannelCountResolver) (Driver, error) { return slow, nil })
+
+	cfg := testConfig()
+	cfg.Output.Drivers = []string{"fake-blocking"}
+	m, err := NewManager(cfg, fixtures.NewStore())
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	m.Dispatch(map[string]float64{"a": 1}, cfg)
+	go m.Flush()
+	<-slow.sending
+
+	dispatched := make(chan struct{})
+	go func() {
+		m.Dispatch(map[string]float64{"a": 0.5}, cfg)
+		close(dispatched)
+	}()
+	select {
+	case <-dispatched:
+	case <-time.After(time.Second):
+		t.Fatal("Dispatch blocked behind a driver's Send")
+	}
+	close(slow.release)
+}
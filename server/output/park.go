@@ -0,0 +1,114 @@
+package output
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/dmx"
+)
+
+// ParkedChannel is a channel pinned to a fixed value.
+type ParkedChannel struct {
+	Universe int `json:"universe"`
+	Channel  int `json:"channel"` // 1-indexed
+	Value    int `json:"value"`   // DMX value 0–255
+}
+
+// Parking pins channels to fixed values regardless of parameter state,
+// blackout or sACN input. Add it as the last processor so it wins over
+// everything else. Parks are runtime-only and are not saved to config.
+type Parking struct {
+	onChange func(universes []int)
+
+	mu     sync.Mutex
+	parked map[int]map[int]byte // universe -> channel -> value
+}
+
+// NewParking creates an empty Parking. onChange is called with the affected
+// universe after every change, typically Manager.Reprocess.
+func NewParking(onChange func(universes []int)) *Parking {
+	return &Parking{onChange: onChange, parked: make(map[int]map[int]byte)}
+}
+
+// Park pins channel in universe to value.
+func (p *Parking) Park(universe, channel int, value byte) {
+	p.mu.Lock()
+	if p.parked[universe] == nil {
+		p.parked[universe] = make(map[int]byte)
+	}
+	p.parked[universe][channel] = value
+	p.mu.Unlock()
+	p.changed([]int{universe})
+}
+
+// Unpark releases channel in universe. It reports whether it was parked.
+func (p *Parking) Unpark(universe, channel int) bool {
+	p.mu.Lock()
+	_, ok := p.parked[universe][channel]
+	delete(p.parked[universe], channel)
+	if len(p.parked[universe]) == 0 {
+		delete(p.parked, universe)
+	}
+	p.mu.Unlock()
+	if ok {
+		p.changed([]int{universe})
+	}
+	return ok
+}
+
+// UnparkAll releases every parked channel.
+func (p *Parking) UnparkAll() {
+	p.mu.Lock()
+	universes := make([]int, 0, len(p.parked))
+	for universe := range p.parked {
+		universes = append(universes, universe)
+	}
+	p.parked = make(map[int]map[int]byte)
+	p.mu.Unlock()
+	sort.Ints(universes)
+	p.changed(universes)
+}
+
+// Parked returns every parked channel, sorted by universe and channel.
+func (p *Parking) Parked() []ParkedChannel {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := []ParkedChannel{}
+	for universe, channels := range p.parked {
+		for channel, value := range channels {
+			out = append(out, ParkedChannel{Universe: universe, Channel: channel, Value: int(value)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Universe != out[j].Universe {
+			return out[i].Universe < out[j].Universe
+		}
+		return out[i].Channel < out[j].Channel
+	})
+	return out
+}
+
+// Process overwrites the parked channels of universe.
+func (p *Parking) Process(universe int, _ config.UniverseConfig, data []byte) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	channels := p.parked[universe]
+	if len(channels) == 0 {
+		return data
+	}
+	out := make([]byte, len(data))
+	copy(out, data)
+	for channel, value := range channels {
+		if channel >= 1 && channel <= len(out) && channel <= dmx.UniverseSize {
+			out[channel-1] = value
+		}
+	}
+	return out
+}
+
+func (p *Parking) changed(universes []int) {
+	if len(universes) > 0 && p.onChange != nil {
+		p.onChange(universes)
+	}
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"fmt"
+	"net"
+)
+
+// UDPSender writes packets to hosts on one port over long-lived sockets, one
+// per host, for drivers of UDP protocols. Like a Driver, it is not safe for
+// concurrent use.
+type UDPSender struct {
+	network string // "udp" or "udp4"
+	port    int
+	conns   map[string]*net.UDPConn
+}
+
+// NewUDPSender creates a sender that dials port on network.
+func NewUDPSender(network string, port int) *UDPSender {
+	return &UDPSender{network: network, port: port, conns: make(map[string]*net.UDPConn)}
+}
+
+// Send writes pkt to host, dialing its socket on first use. A socket that
+// fails to write is dropped so the next send dials a fresh one.
+func (s *UDPSender) Send(host string, pkt []byte) error {
+	conn, err := s.conn(host)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(pkt); err != nil {
+		conn.Close()
+		delete(s.conns, host)
+		return err
+	}
+	return nil
+}
+
+// Close releases the sockets.
+func (s *UDPSender) Close() error {
+	for host, conn := range s.conns {
+		conn.Close()
+		delete(s.conns, host)
+	}
+	return nil
+}
+
+func (s *UDPSender) conn(host string) (*net.UDPConn, error) {
+	if conn, ok := s.conns[host]; ok {
+		return conn, nil
+	}
+	addr, err := net.ResolveUDPAddr(s.network, net.JoinHostPort(host, fmt.Sprint(s.port)))
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP(s.network, nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	s.conns[host] = conn
+	return conn, nil
+}
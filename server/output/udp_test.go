@@ -0,0 +1,39 @@
+package output
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUDPSender(t *testing.T) {
+	ln, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	s := NewUDPSender("udp4", ln.LocalAddr().(*net.UDPAddr).Port)
+	for _, pkt := range []string{"one", "two"} {
+		if err := s.Send("127.0.0.1", []byte(pkt)); err != nil {
+			t.Fatalf("Send(%q): %v", pkt, err)
+		}
+	}
+	if len(s.conns) != 1 {
+		t.Errorf("%d sockets, want one reused for the host", len(s.conns))
+	}
+
+	buf := make([]byte, 16)
+	ln.SetReadDeadline(time.Now().Add(time.Second))
+	for _, want := range []string{"one", "two"} {
+		n, err := ln.Read(buf)
+		if err != nil || string(buf[:n]) != want {
+			t.Fatalf("read %q, %v; want %q", buf[:n], err, want)
+		}
+	}
+
+	s.Close()
+	if len(s.conns) != 0 {
+		t.Errorf("%d sockets left open after Close", len(s.conns))
+	}
+}
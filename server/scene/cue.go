@@ -0,0 +1,536 @@
+package scene
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/dmx"
+)
+
+var (
+	// ErrNoList is returned for a cue list name that isn't configured.
+	ErrNoList = errors.New("no such cue list")
+	// ErrEnd is returned by GO on the last cue and BACK before the first.
+	ErrEnd = errors.New("no cue to go to")
+	// ErrCommand is returned by Command for an unknown command.
+	ErrCommand = errors.New("unknown cue command")
+)
+
+// Cue commands, as accepted by Command.
+const (
+	CommandGo    = "go"
+	CommandBack  = "back"
+	CommandPause = "pause"
+)
+
+// CueStatus is a cue list's playback position.
+type CueStatus struct {
+	Cue     int    `json:"cue"` // 1-based; 0 before the first GO
+	Label   string `json:"label"`
+	Count   int    `json:"count"`
+	Running bool   `json:"running"` // a delay, fade or follow is in progress
+	Paused  bool   `json:"paused"`
+}
+
+// layer is a cue list's look in one universe: its channel values and how
+// much of them is merged over the live output, 0–1.
+type layer struct {
+	data []byte
+	mix  float64
+}
+
+// playback is one cue list's position and the transition into it.
+type playback struct {
+	index    int // current cue, -1 before the first GO
+	from, to map[int]layer
+	cue      config.Cue     // timings of the transition
+	follow   *time.Duration // auto-follow after the transition, if any
+	start    time.Time      // when the transition last (re)started running
+	elapsed  time.Duration  // transition time accumulated before start
+	paused   bool
+	running  bool // the transition or its follow hasn't finished
+	settled  bool // the last frame of the transition has been reprocessed
+}
+
+// gate fades every cue list in and out together, for blackout.
+type gate struct {
+	from, to float64
+	start    time.Time
+	dur      time.Duration
+}
+
+// Cues plays the cue lists in cfg.Cues back from the scene store and layers
+// them over the live output as an output processor, merged by
+// cfg.Cues.MergeRule. Add it before the masters so cues are scaled like the
+// rest of Penumbra's output. Playback is runtime-only: every list starts
+// with no cue taken, leaving the live output untouched.
+type Cues struct {
+	store     *Store
+	cfg       *config.Config
+	resolve   config.FixtureResolver
+	reprocess func(universes []int)
+	now       func() time.Time
+
+	mu    sync.Mutex
+	lists map[string]*playback
+	gate  gate
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewCues creates the cue playback. reprocess is called with the universes
+// whose layer changed, typically output.Manager.Reprocess. Call Run in a
+// goroutine to advance fades and follows.
+func NewCues(store *Store, cfg *config.Config, resolve config.FixtureResolver, reprocess func(universes []int)) *Cues {
+	return &Cues{
+		store:     store,
+		cfg:       cfg,
+		resolve:   resolve,
+		reprocess: reprocess,
+		now:       time.Now,
+		lists:     make(map[string]*playback),
+		gate:      gate{from: 1, to: 1},
+		done:      make(chan struct{}),
+	}
+}
+
+// Command runs the named command on list.
+func (c *Cues) Command(list, command string) error {
+	switch command {
+	case CommandGo:
+		return c.Go(list)
+	case CommandBack:
+		return c.Back(list)
+	case CommandPause:
+		return c.Pause(list)
+	}
+	return fmt.Errorf("%w %q (want go, back or pause)", ErrCommand, command)
+}
+
+// Go takes the next cue of list, or resumes a paused transition.
+func (c *Cues) Go(list string) error {
+	return c.command(list, func(pb *playback, cues []config.Cue, now time.Time) error {
+		if pb.paused {
+			pb.resume(now)
+			return nil
+		}
+		next := pb.index + 1
+		if next >= len(cues) {
+			return ErrEnd
+		}
+		return c.take(pb, next, cues[next], true, now)
+	})
+}
+
+// Back returns list to the previous cue, using that cue's fade times without
+// its delay or follow. Back from the first cue fades the list out.
+func (c *Cues) Back(list string) error {
+	return c.command(list, func(pb *playback, cues []config.Cue, now time.Time) error {
+		if pb.index < 0 {
+			return ErrEnd
+		}
+		prev := min(pb.index, len(cues)) - 1
+		if prev < 0 {
+			var cue config.Cue
+			if len(cues) > 0 {
+				cue.FadeOutMs = cues[0].FadeOutMs
+			}
+			return c.take(pb, -1, cue, false, now)
+		}
+		cue := cues[prev]
+		cue.DelayMs, cue.FollowMs = 0, nil
+		return c.take(pb, prev, cue, false, now)
+	})
+}
+
+// Pause freezes the running transition of list, or resumes it if paused.
+func (c *Cues) Pause(list string) error {
+	return c.command(list, func(pb *playback, _ []config.Cue, now time.Time) error {
+		switch {
+		case pb.paused:
+			pb.resume(now)
+		case pb.running:
+			pb.elapsed = pb.pos(now)
+			pb.paused = true
+		}
+		return nil
+	})
+}
+
+// Suspend fades every cue list out over fade, e.g. for blackout, and refuses
+// commands until Resume. Positions are kept.
+func (c *Cues) Suspend(fade time.Duration) {
+	c.setGate(0, fade)
+}
+
+// Resume fades the cue lists back in over fade after Suspend.
+func (c *Cues) Resume(fade time.Duration) {
+	c.setGate(1, fade)
+}
+
+// Status returns the position of every configured cue list.
+func (c *Cues) Status() map[string]CueStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]CueStatus, len(c.cfg.Cues.Lists))
+	for name, cues := range c.cfg.Cues.Lists {
+		st := CueStatus{Count: len(cues)}
+		if pb, ok := c.lists[name]; ok {
+			st.Cue = pb.index + 1
+			st.Running = pb.running
+			st.Paused = pb.paused
+			if pb.index >= 0 && pb.index < len(cues) {
+				st.Label = cues[pb.index].Label
+			}
+		}
+		out[name] = st
+	}
+	return out
+}
+
+// Process merges every cue list's layer over the universe's frame, in list
+// name order.
+func (c *Cues) Process(universe int, _ config.UniverseConfig, data []byte) []byte {
+	now := c.now()
+	c.mu.Lock()
+	g := c.gate.level(now)
+	targets := c.cfg.UniverseTargets(universe)
+	var layers []layer
+	for _, name := range c.names() {
+		if l, ok := c.lists[name].layer(universe, now, targets); ok && l.mix*g > 0 {
+			l.mix *= g
+			layers = append(layers, l)
+		}
+	}
+	rule := c.cfg.Cues.MergeRule()
+	c.mu.Unlock()
+	if len(layers) == 0 {
+		return data
+	}
+
+	out := make([]byte, len(data))
+	copy(out, data)
+	for _, l := range layers {
+		if rule == config.MergeLTP {
+			out = dmx.Blend(out, resize(l.data, len(out)), l.mix, targets)
+			continue
+		}
+		scaled := dmx.Blend(make([]byte, len(out)), resize(l.data, len(out)), l.mix, targets)
+		mergeHTP(out, scaled, targets)
+	}
+	return out
+}
+
+// Run advances transitions, gate fades and follows on a fixed ticker. Blocks
+// until Close.
+func (c *Cues) Run() {
+	ticker := time.NewTicker(time.Second / time.Duration(c.cfg.Output.RefreshHz))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.tick(now)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Close stops Run. Safe to call more than once.
+func (c *Cues) Close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// tick reprocesses every universe whose layer is changing and takes any cue
+// whose follow time has passed.
+func (c *Cues) tick(now time.Time) {
+	c.mu.Lock()
+	changed := make(map[int]bool)
+	if c.gate.dur > 0 {
+		for _, pb := range c.lists {
+			for universe := range pb.universes() {
+				changed[universe] = true
+			}
+		}
+		if now.Sub(c.gate.start) >= c.gate.dur {
+			c.gate.dur = 0
+		}
+	}
+	for _, name := range c.names() {
+		pb := c.lists[name]
+		if !pb.running || pb.paused || c.gate.to == 0 {
+			continue
+		}
+		pos, end := pb.pos(now), pb.cue.Duration()
+		if !pb.settled {
+			for universe := range pb.universes() {
+				changed[universe] = true
+			}
+			pb.settled = pos >= end
+		}
+		if pb.follow == nil {
+			pb.running = !pb.settled
+			continue
+		}
+		if pos < end+*pb.follow {
+			continue
+		}
+		pb.running = false
+		cues := c.cfg.Cues.Lists[name]
+		if next := pb.index + 1; next < len(cues) {
+			if err := c.take(pb, next, cues[next], true, now); err != nil {
+				log.Printf("cues: %s: follow to cue %d: %v", name, next+1, err)
+				continue
+			}
+			for universe := range pb.universes() {
+				changed[universe] = true
+			}
+		}
+	}
+	c.mu.Unlock()
+	if len(changed) > 0 {
+		c.reprocess(sortedKeys(changed))
+	}
+}
+
+// command runs fn on the named list's playback with c.mu held, then
+// reprocesses the list's universes.
+func (c *Cues) command(list string, fn func(pb *playback, cues []config.Cue, now time.Time) error) error {
+	now := c.now()
+	c.mu.Lock()
+	cues, ok := c.cfg.Cues.Lists[list]
+	if !ok {
+		c.mu.Unlock()
+		return fmt.Errorf("%w: %q", ErrNoList, list)
+	}
+	if c.gate.to == 0 {
+		c.mu.Unlock()
+		return ErrBlackout
+	}
+	pb, ok := c.lists[list]
+	if !ok {
+		pb = &playback{index: -1}
+		c.lists[list] = pb
+	}
+	before := pb.universes()
+	err := fn(pb, cues, now)
+	after := pb.universes()
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for universe := range before {
+		after[universe] = true
+	}
+	c.reprocess(sortedKeys(after))
+	return nil
+}
+
+// take starts the transition of pb to cue index (-1 for none) with the
+// timings of cue. Must be called with c.mu held.
+func (c *Cues) take(pb *playback, index int, cue config.Cue, follow bool, now time.Time) error {
+	to := make(map[int]layer)
+	if index >= 0 {
+		sc, ok := c.store.Get(cue.Scene)
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrNotFound, cue.Scene)
+		}
+		frames := sc.Frames()
+		if sc.Kind == KindState {
+			frames = dmx.Render(sc.State, c.cfg, c.resolve)
+		}
+		for universe, frame := range frames {
+			if _, ok := c.cfg.Universes[universe]; ok {
+				to[universe] = layer{data: frame, mix: 1}
+			}
+		}
+	}
+	from := make(map[int]layer)
+	for universe := range pb.universes() {
+		if l, ok := pb.layer(universe, now, c.cfg.UniverseTargets(universe)); ok {
+			from[universe] = l
+		}
+	}
+	pb.index, pb.from, pb.to, pb.cue = index, from, to, cue
+	pb.follow = nil
+	if d, ok := cue.Follow(); ok && follow {
+		pb.follow = &d
+	}
+	pb.start, pb.elapsed = now, 0
+	pb.paused, pb.running, pb.settled = false, true, false
+	return nil
+}
+
+// setGate fades the gate to level over fade.
+func (c *Cues) setGate(level float64, fade time.Duration) {
+	now := c.now()
+	c.mu.Lock()
+	c.gate = gate{from: c.gate.level(now), to: level, start: now, dur: fade}
+	universes := make(map[int]bool)
+	for _, pb := range c.lists {
+		for universe := range pb.universes() {
+			universes[universe] = true
+		}
+	}
+	c.mu.Unlock()
+	c.reprocess(sortedKeys(universes))
+}
+
+// names returns the playing lists in name order. Must be called with c.mu
+// held.
+func (c *Cues) names() []string {
+	names := make([]string, 0, len(c.lists))
+	for name := range c.lists {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// pos returns how far the transition has run at now.
+func (pb *playback) pos(now time.Time) time.Duration {
+	if pb.paused {
+		return pb.elapsed
+	}
+	return pb.elapsed + now.Sub(pb.start)
+}
+
+func (pb *playback) resume(now time.Time) {
+	pb.start = now
+	pb.paused = false
+}
+
+// universes returns every universe the transition touches.
+func (pb *playback) universes() map[int]bool {
+	out := make(map[int]bool)
+	for universe := range pb.from {
+		out[universe] = true
+	}
+	for universe := range pb.to {
+		out[universe] = true
+	}
+	return out
+}
+
+// layer returns the list's look in universe at now. A universe missing from
+// one end of the transition keeps the other end's values and fades its mix.
+func (pb *playback) layer(universe int, now time.Time, targets map[string][]config.ChannelTarget) (layer, bool) {
+	from, fromOK := pb.from[universe]
+	to, toOK := pb.to[universe]
+	switch {
+	case !fromOK && !toOK:
+		return layer{}, false
+	case !fromOK:
+		from = layer{data: to.data}
+	case !toOK:
+		to = layer{data: from.data}
+	}
+	t := pb.pos(now) - pb.cue.Delay()
+	in, out := progress(t, pb.cue.FadeIn()), progress(t, pb.cue.FadeOut())
+	mix := from.mix + (to.mix-from.mix)*out
+	if to.mix > from.mix {
+		mix = from.mix + (to.mix-from.mix)*in
+	}
+	return layer{data: splitBlend(from.data, to.data, in, out, targets), mix: mix}, true
+}
+
+// level returns the gate level at now.
+func (g gate) level(now time.Time) float64 {
+	p := progress(now.Sub(g.start), g.dur)
+	return g.from + (g.to-g.from)*p
+}
+
+// progress returns how far through a fade of length d the time t is, 0–1.
+func progress(t, d time.Duration) float64 {
+	switch {
+	case t <= 0 && d > 0:
+		return 0
+	case t >= d:
+		return 1
+	}
+	return float64(t) / float64(d)
+}
+
+// splitBlend blends from toward to, moving rising channels by in and falling
+// channels by out. 16-bit targets move as one value.
+func splitBlend(from, to []byte, in, out float64, targets map[string][]config.ChannelTarget) []byte {
+	rising := dmx.Blend(from, to, in, targets)
+	if in == out {
+		return rising
+	}
+	falling := dmx.Blend(from, to, out, targets)
+	data := make([]byte, len(to))
+	for i := range data {
+		data[i] = falling[i]
+		if i >= len(from) || to[i] > from[i] {
+			data[i] = rising[i]
+		}
+	}
+	for _, tt := range targets {
+		for _, t := range tt {
+			c, f := t.Channel-1, t.Fine()-1
+			if !t.Is16Bit() || c < 0 || f < 0 || c >= len(to) || f >= len(to) || c >= len(from) || f >= len(from) {
+				continue
+			}
+			src := falling
+			if uint16(to[c])<<8|uint16(to[f]) > uint16(from[c])<<8|uint16(from[f]) {
+				src = rising
+			}
+			data[c], data[f] = src[c], src[f]
+		}
+	}
+	return data
+}
+
+// mergeHTP raises dst to src per channel. 16-bit targets compare as one
+// value.
+func mergeHTP(dst, src []byte, targets map[string][]config.ChannelTarget) {
+	fine := make(map[int]int) // fine channel index -> coarse channel index
+	paired := make(map[int]bool)
+	for _, tt := range targets {
+		for _, t := range tt {
+			if t.Is16Bit() {
+				fine[t.Fine()-1] = t.Channel - 1
+				paired[t.Channel-1], paired[t.Fine()-1] = true, true
+			}
+		}
+	}
+	for i := range dst {
+		if i < len(src) && !paired[i] {
+			dst[i] = max(dst[i], src[i])
+		}
+	}
+	for f, c := range fine {
+		if c < 0 || f < 0 || c >= len(dst) || f >= len(dst) || c >= len(src) || f >= len(src) {
+			continue
+		}
+		if uint16(src[c])<<8|uint16(src[f]) > uint16(dst[c])<<8|uint16(dst[f]) {
+			dst[c], dst[f] = src[c], src[f]
+		}
+	}
+}
+
+// resize returns data padded with zeros or truncated to n channels.
+func resize(data []byte, n int) []byte {
+	if len(data) == n {
+		return data
+	}
+	out := make([]byte, n)
+	copy(out, data)
+	return out
+}
+
+func sortedKeys(m map[int]bool) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
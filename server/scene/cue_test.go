@@ -0,0 +1,174 @@
+package scene
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+)
+
+// cueRig is a Cues on a fake clock with one universe and three DMX scenes:
+// "a" sets channel 1, "b" channel 2 and "c" channel 1 at half.
+type cueRig struct {
+	cues *Cues
+	now  time.Time
+}
+
+func newCueRig(t *testing.T, merge string, list []config.Cue) *cueRig {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sc := range []Scene{
+		{Name: "a", Kind: KindDMX, DMX: map[int][]int{1: {255}}},
+		{Name: "b", Kind: KindDMX, DMX: map[int][]int{1: {0, 255}}},
+		{Name: "c", Kind: KindDMX, DMX: map[int][]int{1: {128}}},
+	} {
+		if err := store.Put(sc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &config.Config{
+		Universes: map[int]config.UniverseConfig{1: {}},
+		Output:    config.OutputConfig{RefreshHz: 40},
+		Cues:      config.CueConfig{Merge: merge, Lists: map[string][]config.Cue{"main": list}},
+	}
+	r := &cueRig{now: time.Unix(1000, 0)}
+	r.cues = NewCues(store, cfg, nil, func([]int) {})
+	r.cues.now = func() time.Time { return r.now }
+	return r
+}
+
+// at advances the clock to ms after the start and runs a tick.
+func (r *cueRig) at(ms int) {
+	r.now = time.Unix(1000, 0).Add(time.Duration(ms) * time.Millisecond)
+	r.cues.tick(r.now)
+}
+
+// out returns the first two channels of the live frame live after the cues.
+func (r *cueRig) out(live ...byte) [2]byte {
+	data := make([]byte, 512)
+	copy(data, live)
+	data = r.cues.Process(1, config.UniverseConfig{}, data)
+	return [2]byte{data[0], data[1]}
+}
+
+func follow(ms int) *int { return &ms }
+
+func TestCues_HTP(t *testing.T) {
+	r := newCueRig(t, "", []config.Cue{{Scene: "a", FadeInMs: 1000}})
+	if got := r.out(50); got != [2]byte{50, 0} {
+		t.Fatalf("before GO: %v, want live output", got)
+	}
+	if err := r.cues.Go("main"); err != nil {
+		t.Fatal(err)
+	}
+	r.at(500)
+	if got := r.out(50); got[0] != 128 {
+		t.Errorf("half way: channel 1 = %d, want 128", got[0])
+	}
+	r.at(1000)
+	if got := r.out(50); got[0] != 255 {
+		t.Errorf("complete: channel 1 = %d, want 255", got[0])
+	}
+	if err := r.cues.Go("main"); !errors.Is(err, ErrEnd) {
+		t.Errorf("GO past the last cue: %v, want ErrEnd", err)
+	}
+}
+
+func TestCues_LTP(t *testing.T) {
+	r := newCueRig(t, config.MergeLTP, []config.Cue{{Scene: "c", FadeInMs: 1000}})
+	r.cues.Go("main")
+	r.at(500)
+	if got := r.out(200); got[0] != 164 {
+		t.Errorf("half way: channel 1 = %d, want 164", got[0])
+	}
+	r.at(1000)
+	if got := r.out(200); got[0] != 128 {
+		t.Errorf("complete: channel 1 = %d, want the cue's 128 over live 200", got[0])
+	}
+}
+
+func TestCues_SplitFade(t *testing.T) {
+	r := newCueRig(t, "", []config.Cue{
+		{Scene: "a"},
+		{Scene: "b", FadeInMs: 1000, FadeOutMs: 2000, DelayMs: 500},
+	})
+	r.cues.Go("main")
+	r.at(0)
+	r.cues.Go("main")
+	r.at(500)
+	if got := r.out(); got != [2]byte{255, 0} {
+		t.Errorf("during delay: %v, want cue a", got)
+	}
+	r.at(1500)
+	if got := r.out(); got != [2]byte{128, 255} {
+		t.Errorf("fade in done: %v, want [128 255]", got)
+	}
+	r.at(2500)
+	if got := r.out(); got != [2]byte{0, 255} {
+		t.Errorf("fade out done: %v, want cue b", got)
+	}
+}
+
+func TestCues_FollowPauseBack(t *testing.T) {
+	r := newCueRig(t, "", []config.Cue{
+		{Scene: "a", FadeInMs: 1000, FollowMs: follow(500)},
+		{Scene: "b"},
+	})
+	r.cues.Go("main")
+	r.at(500)
+	r.cues.Pause("main")
+	r.at(5000)
+	if st := r.cues.Status()["main"]; st.Cue != 1 || !st.Paused {
+		t.Fatalf("paused: %+v", st)
+	}
+	if got := r.out(); got[0] != 128 {
+		t.Errorf("paused half way: channel 1 = %d, want 128", got[0])
+	}
+
+	r.cues.Go("main") // resumes
+	r.at(5500)
+	if st := r.cues.Status()["main"]; st.Cue != 1 || st.Paused {
+		t.Fatalf("resumed, follow pending: %+v", st)
+	}
+	r.at(6000)
+	if st := r.cues.Status()["main"]; st.Cue != 2 {
+		t.Fatalf("after follow: %+v, want cue 2", st)
+	}
+	if got := r.out(); got != [2]byte{0, 255} {
+		t.Errorf("after follow: %v, want cue b", got)
+	}
+
+	r.cues.Back("main") // fades in over cue a's 1000ms
+	r.at(7000)
+	if got := r.out(); got != [2]byte{255, 0} {
+		t.Errorf("after BACK: %v, want cue a", got)
+	}
+	r.cues.Back("main")
+	if got := r.out(9); got != [2]byte{9, 0} {
+		t.Errorf("BACK from the first cue: %v, want live output", got)
+	}
+}
+
+func TestCues_Suspend(t *testing.T) {
+	r := newCueRig(t, "", []config.Cue{{Scene: "a"}, {Scene: "b"}})
+	r.cues.Go("main")
+	r.cues.Suspend(0)
+	if got := r.out(); got[0] != 0 {
+		t.Errorf("suspended: channel 1 = %d, want 0", got[0])
+	}
+	if err := r.cues.Go("main"); !errors.Is(err, ErrBlackout) {
+		t.Errorf("GO while suspended: %v, want ErrBlackout", err)
+	}
+	r.cues.Resume(0)
+	if got := r.out(); got[0] != 255 {
+		t.Errorf("resumed: channel 1 = %d, want 255", got[0])
+	}
+	if err := r.cues.Go("other"); !errors.Is(err, ErrNoList) {
+		t.Errorf("GO on unknown list: %v, want ErrNoList", err)
+	}
+}
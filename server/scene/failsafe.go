@@ -0,0 +1,175 @@
+package scene
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/output"
+)
+
+// failsafeInterval is how often the emitter state is checked.
+const failsafeInterval = 250 * time.Millisecond
+
+// FailsafeStatus is the failsafe's configured action and whether it has
+// tripped.
+type FailsafeStatus struct {
+	Action string `json:"action"`
+	Scene  string `json:"scene,omitempty"`
+	Active bool   `json:"active"`
+}
+
+// Failsafe shows cfg.Emitter.Failsafe's look once the emitter has been quiet
+// long enough, and fades back to live state when packets resume. It is armed
+// by the first packet (see Restore), so it doesn't trip before an emitter has
+// ever been heard from. While it is active, live state should not be
+// dispatched (see Active). It leaves the output alone while src.Blocked
+// reports another look, such as blackout.
+type Failsafe struct {
+	store    *Store
+	cfg      *config.Config
+	outputs  *output.Manager
+	src      Sources
+	onChange func(FailsafeStatus)
+
+	mu     sync.Mutex
+	armed  bool // an emitter has been heard from
+	active bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewFailsafe creates a failsafe that is not yet armed. onChange (may be nil)
+// is called whenever it trips or restores. Call Run in a goroutine.
+func NewFailsafe(store *Store, cfg *config.Config, outputs *output.Manager, src Sources, onChange func(FailsafeStatus)) *Failsafe {
+	return &Failsafe{
+		store:    store,
+		cfg:      cfg,
+		outputs:  outputs,
+		src:      src,
+		onChange: onChange,
+		done:     make(chan struct{}),
+	}
+}
+
+// Active reports whether the failsafe has tripped.
+func (f *Failsafe) Active() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active
+}
+
+// Status returns the failsafe's action and state.
+func (f *Failsafe) Status() FailsafeStatus {
+	fs := f.cfg.Emitter.Failsafe
+	st := FailsafeStatus{Action: fs.Action, Active: f.Active()}
+	if fs.Action == config.FailsafeScene {
+		st.Scene = fs.Scene
+	}
+	return st
+}
+
+// Check trips the failsafe if it is armed and the emitter state has reached
+// the configured trigger, fading to the failsafe look. Run calls it
+// periodically.
+func (f *Failsafe) Check() {
+	fs := f.cfg.Emitter.Failsafe
+	if f.src.Emitter() > fs.Trigger() {
+		return
+	}
+	f.mu.Lock()
+	if !f.armed || f.active {
+		f.mu.Unlock()
+		return
+	}
+	f.active = true
+	f.mu.Unlock()
+	log.Printf("failsafe: emitter %s, %s", f.src.Emitter(), fs.Action)
+	if !f.blocked() {
+		f.outputs.Fade(fs.Fade())
+		f.show()
+	}
+	f.changed()
+}
+
+// Restore arms the failsafe and fades back from the failsafe look to live
+// state; call it on every packet. It reports whether the failsafe was
+// active.
+func (f *Failsafe) Restore() bool {
+	f.mu.Lock()
+	active := f.active
+	f.armed, f.active = true, false
+	f.mu.Unlock()
+	if !active {
+		return false
+	}
+	log.Printf("failsafe: emitter back, restoring live output")
+	if !f.blocked() {
+		f.outputs.Fade(f.cfg.Emitter.Failsafe.Restore())
+		f.outputs.Dispatch(f.src.Live(), f.cfg)
+	}
+	f.changed()
+	return true
+}
+
+// Apply re-dispatches the failsafe look if it is active, e.g. on reset from
+// blackout or after a config change, and reports whether it is.
+func (f *Failsafe) Apply() bool {
+	if !f.Active() {
+		return false
+	}
+	f.show()
+	return true
+}
+
+// Run checks the emitter state on a fixed ticker. Blocks until Close.
+func (f *Failsafe) Run() {
+	ticker := time.NewTicker(failsafeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.Check()
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// Close stops Run. Safe to call more than once.
+func (f *Failsafe) Close() {
+	f.closeOnce.Do(func() { close(f.done) })
+}
+
+// show dispatches the failsafe look. Hold dispatches nothing, keeping the
+// last frames.
+func (f *Failsafe) show() {
+	fs := f.cfg.Emitter.Failsafe
+	switch fs.Action {
+	case config.FailsafeBlackout:
+		if len(f.cfg.BlackoutScene) == 0 {
+			f.outputs.DispatchBlackout(f.cfg)
+		} else {
+			f.outputs.Dispatch(f.cfg.BlackoutScene, f.cfg)
+		}
+	case config.FailsafeScene:
+		sc, ok := f.store.Get(fs.Scene)
+		if !ok {
+			log.Printf("failsafe: %v: %q", ErrNotFound, fs.Scene)
+			return
+		}
+		show(f.outputs, f.cfg, sc)
+	}
+}
+
+func (f *Failsafe) blocked() bool {
+	return f.src.Blocked != nil && f.src.Blocked()
+}
+
+func (f *Failsafe) changed() {
+	if f.onChange != nil {
+		f.onChange(f.Status())
+	}
+}
@@ -0,0 +1,90 @@
+package scene
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/fixtures"
+	"github.com/footgunz/penumbra/output"
+)
+
+func TestFailsafe(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(Scene{Name: "house", Kind: KindDMX, DMX: map[int][]int{1: {200}}}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Universes:  map[int]config.UniverseConfig{1: {}},
+		Parameters: map[string]config.ParameterConfig{"a": {{Universe: 1, Channel: 1}}},
+		Emitter: config.EmitterConfig{Failsafe: config.FailsafeConfig{
+			Action: config.FailsafeScene, Scene: "house", After: "idle",
+		}},
+	}
+	outputs, err := output.NewManager(cfg, fixtures.NewStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := map[string]float64{"a": 1}
+	outputs.Dispatch(live, cfg)
+
+	emitter, blocked, changes := config.EmitterConnected, false, 0
+	f := NewFailsafe(store, cfg, outputs, Sources{
+		Live:    func() map[string]float64 { return live },
+		Blocked: func() bool { return blocked },
+		Emitter: func() config.EmitterState { return emitter },
+	}, func(FailsafeStatus) { changes++ })
+	channel1 := func() byte { return outputs.Rendered()[1][0] }
+
+	// Nothing has been heard from yet: a lost emitter doesn't trip it.
+	emitter = config.EmitterDisconnected
+	f.Check()
+	if f.Active() || channel1() != 255 {
+		t.Fatalf("before the first packet: active %v, channel 1 = %d", f.Active(), channel1())
+	}
+
+	emitter = config.EmitterConnected
+	if f.Restore() {
+		t.Error("first packet reported the failsafe active")
+	}
+	f.Check()
+	if f.Active() || channel1() != 255 {
+		t.Fatalf("connected: active %v, channel 1 = %d", f.Active(), channel1())
+	}
+
+	emitter = config.EmitterIdle
+	f.Check()
+	f.Check()
+	if !f.Active() || channel1() != 200 {
+		t.Fatalf("idle: active %v, channel 1 = %d, want the failsafe scene", f.Active(), channel1())
+	}
+	if st := f.Status(); st.Action != config.FailsafeScene || st.Scene != "house" || !st.Active {
+		t.Errorf("Status() = %+v", st)
+	}
+
+	emitter = config.EmitterConnected
+	if !f.Restore() || f.Active() || channel1() != 255 {
+		t.Fatalf("restored: active %v, channel 1 = %d, want live", f.Active(), channel1())
+	}
+	if f.Restore() {
+		t.Error("second Restore reported the failsafe active")
+	}
+	if changes != 2 {
+		t.Errorf("onChange called %d times, want 2", changes)
+	}
+
+	// Blackout owns the output: the failsafe trips without dispatching, and
+	// shows its look once Apply is called on reset.
+	blocked, emitter = true, config.EmitterDisconnected
+	f.Check()
+	if !f.Active() || channel1() != 255 {
+		t.Fatalf("blocked: active %v, channel 1 = %d, want untouched", f.Active(), channel1())
+	}
+	blocked = false
+	if !f.Apply() || channel1() != 200 {
+		t.Errorf("Apply: channel 1 = %d, want the failsafe scene", channel1())
+	}
+}
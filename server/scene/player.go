@@ -0,0 +1,157 @@
+package scene
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/output"
+)
+
+var (
+	// ErrNotFound is returned for a scene name that isn't stored.
+	ErrNotFound = errors.New("no such scene")
+	// ErrBlackout is returned when recalling a scene without hold during
+	// blackout, since it would never be seen.
+	ErrBlackout = errors.New("blackout is active")
+)
+
+// Sources are the live inputs scene playback captures from and returns to.
+type Sources struct {
+	Snapshot func() map[string]float64  // emitter parameter state, for capture
+	Live     func() map[string]float64  // rendered live state, to release back to
+	Blocked  func() bool                // reports a look that playback must not override
+	Emitter  func() config.EmitterState // emitter connection state, for the failsafe
+}
+
+// Player captures scenes into a Store and recalls them into the output.
+// A recalled scene is replaced by the next change in live state unless it is
+// held; while a scene is held, live state is not dispatched (see Held).
+type Player struct {
+	store   *Store
+	cfg     *config.Config
+	outputs *output.Manager
+	src     Sources
+	now     func() time.Time
+
+	mu   sync.Mutex
+	held string
+}
+
+// NewPlayer creates a Player with no scene held.
+func NewPlayer(store *Store, cfg *config.Config, outputs *output.Manager, src Sources) *Player {
+	return &Player{store: store, cfg: cfg, outputs: outputs, src: src, now: time.Now}
+}
+
+// Store returns the scene store.
+func (p *Player) Store() *Store {
+	return p.store
+}
+
+// Capture stores the current look as the scene name, replacing any scene of
+// that name. kind KindState captures the emitter's parameter values,
+// KindDMX the rendered DMX frames.
+func (p *Player) Capture(name, kind string) (Scene, error) {
+	sc := Scene{Name: name, Kind: kind, Created: p.now().UnixMilli()}
+	switch kind {
+	case KindState:
+		sc.State = p.src.Snapshot()
+	case KindDMX:
+		sc.DMX = make(map[int][]int)
+		for universe, frame := range p.outputs.Rendered() {
+			values := make([]int, len(frame))
+			for i, v := range frame {
+				values[i] = int(v)
+			}
+			sc.DMX[universe] = values
+		}
+	}
+	if err := p.store.Put(sc); err != nil {
+		return Scene{}, err
+	}
+	return sc, nil
+}
+
+// Recall fades to the named scene over fade. With hold, the scene stays up
+// until Release; during blackout it is held and shown on reset.
+func (p *Player) Recall(name string, fade time.Duration, hold bool) error {
+	sc, ok := p.store.Get(name)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	blocked := p.src.Blocked != nil && p.src.Blocked()
+	if blocked && !hold {
+		return ErrBlackout
+	}
+	p.mu.Lock()
+	if hold {
+		p.held = name
+	} else {
+		p.held = ""
+	}
+	p.mu.Unlock()
+	if !blocked {
+		p.outputs.Fade(fade)
+		p.show(sc)
+	}
+	return nil
+}
+
+// Release drops the held scene and fades back to live state over fade. It
+// reports whether a scene was held.
+func (p *Player) Release(fade time.Duration) bool {
+	p.mu.Lock()
+	held := p.held
+	p.held = ""
+	p.mu.Unlock()
+	if held == "" {
+		return false
+	}
+	if p.src.Blocked == nil || !p.src.Blocked() {
+		p.outputs.Fade(fade)
+		p.outputs.Dispatch(p.src.Live(), p.cfg)
+	}
+	return true
+}
+
+// Held returns the name of the held scene, or "" if none is.
+func (p *Player) Held() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.held
+}
+
+// Restore re-dispatches the held scene, e.g. on reset from blackout or
+// after a config change. It reports whether a scene is held; a held scene
+// deleted from the store is released.
+func (p *Player) Restore() bool {
+	name := p.Held()
+	if name == "" {
+		return false
+	}
+	sc, ok := p.store.Get(name)
+	if !ok {
+		p.mu.Lock()
+		p.held = ""
+		p.mu.Unlock()
+		return false
+	}
+	p.show(sc)
+	return true
+}
+
+func (p *Player) show(sc Scene) {
+	show(p.outputs, p.cfg, sc)
+}
+
+// show dispatches a scene to outputs.
+func show(outputs *output.Manager, cfg *config.Config, sc Scene) {
+	switch sc.Kind {
+	case KindState:
+		outputs.Dispatch(sc.State, cfg)
+	case KindDMX:
+		outputs.DispatchFrames(sc.Frames(), cfg)
+	}
+}
@@ -0,0 +1,172 @@
+// Package scene stores named looks on disk and plays them back. A scene is
+// either a set of parameter values, rendered like emitter state, or raw
+// rendered DMX frames.
+package scene
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// File is the name of the file, next to config.json, that holds the scenes.
+const File = "scenes.json"
+
+// Scene kinds.
+const (
+	KindState = "state" // parameter values
+	KindDMX   = "dmx"   // rendered DMX frames
+)
+
+// Scene is a stored look. State scenes render like emitter state, so
+// parameters the scene doesn't set go to 0. DMX scenes replace only the
+// universes they contain.
+type Scene struct {
+	Name    string             `json:"name"`
+	Kind    string             `json:"kind"`
+	State   map[string]float64 `json:"state,omitempty"`
+	DMX     map[int][]int      `json:"dmx,omitempty"` // universe -> 512 channel values
+	Created int64              `json:"created"`       // unix ms
+}
+
+// Frames returns a DMX scene's frames as byte slices.
+func (s Scene) Frames() map[int][]byte {
+	frames := make(map[int][]byte, len(s.DMX))
+	for universe, values := range s.DMX {
+		frame := make([]byte, 512)
+		for i, v := range values {
+			if i < len(frame) {
+				frame[i] = byte(max(0, min(255, v)))
+			}
+		}
+		frames[universe] = frame
+	}
+	return frames
+}
+
+// Validate checks a scene's name and contents.
+func (s Scene) Validate() error {
+	if s.Name == "" || strings.ContainsAny(s.Name, "/?#") {
+		return fmt.Errorf("scene name %q must be non-empty and contain no / ? #", s.Name)
+	}
+	switch s.Kind {
+	case KindState:
+		for param, v := range s.State {
+			if v < 0 || v > 1 {
+				return fmt.Errorf("scene %q: %s = %v out of range 0-1", s.Name, param, v)
+			}
+		}
+	case KindDMX:
+		for universe, values := range s.DMX {
+			if len(values) > 512 {
+				return fmt.Errorf("scene %q: universe %d has %d channels, max 512", s.Name, universe, len(values))
+			}
+		}
+	default:
+		return fmt.Errorf("scene %q: unknown kind %q (want state or dmx)", s.Name, s.Kind)
+	}
+	return nil
+}
+
+// Store holds the scenes and persists every change to its file.
+type Store struct {
+	path string
+
+	mu     sync.Mutex
+	scenes map[string]Scene
+}
+
+// Open loads the scenes stored at path. A missing file is an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, scenes: make(map[string]Scene)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scenes %s: %w", path, err)
+	}
+	var list []Scene
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("scenes %s: %w", path, err)
+	}
+	for _, sc := range list {
+		s.scenes[sc.Name] = sc
+	}
+	return s, nil
+}
+
+// List returns every scene, sorted by name.
+func (s *Store) List() []Scene {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list()
+}
+
+// Get returns the named scene.
+func (s *Store) Get(name string) (Scene, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, ok := s.scenes[name]
+	return sc, ok
+}
+
+// Put adds or replaces a scene and saves the store.
+func (s *Store) Put(sc Scene) error {
+	if err := sc.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, existed := s.scenes[sc.Name]
+	s.scenes[sc.Name] = sc
+	if err := s.save(); err != nil {
+		if existed {
+			s.scenes[sc.Name] = prev
+		} else {
+			delete(s.scenes, sc.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the named scene and saves the store. It reports whether the
+// scene existed.
+func (s *Store) Delete(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, ok := s.scenes[name]
+	if !ok {
+		return false, nil
+	}
+	delete(s.scenes, name)
+	if err := s.save(); err != nil {
+		s.scenes[name] = sc
+		return true, err
+	}
+	return true, nil
+}
+
+// list must be called with s.mu held.
+func (s *Store) list() []Scene {
+	out := make([]Scene, 0, len(s.scenes))
+	for _, sc := range s.scenes {
+		out = append(out, sc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// save must be called with s.mu held.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
@@ -0,0 +1,91 @@
+package scene
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), File)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open missing file: %v", err)
+	}
+	if got := s.List(); len(got) != 0 {
+		t.Fatalf("empty store lists %d scenes", len(got))
+	}
+
+	warm := Scene{Name: "warm", Kind: KindState, State: map[string]float64{"a": 0.5}}
+	house := Scene{Name: "house", Kind: KindDMX, DMX: map[int][]int{1: {255, 128}}}
+	for _, sc := range []Scene{warm, house} {
+		if err := s.Put(sc); err != nil {
+			t.Fatalf("Put %s: %v", sc.Name, err)
+		}
+	}
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	list := s.List()
+	if len(list) != 2 || list[0].Name != "house" || list[1].Name != "warm" {
+		t.Fatalf("List = %+v, want house, warm", list)
+	}
+	if got, _ := s.Get("warm"); got.State["a"] != 0.5 {
+		t.Errorf("warm state = %v", got.State)
+	}
+	frame := list[0].Frames()[1]
+	if len(frame) != 512 || frame[0] != 255 || frame[1] != 128 || frame[2] != 0 {
+		t.Errorf("house frame starts %v", frame[:3])
+	}
+
+	if ok, err := s.Delete("warm"); !ok || err != nil {
+		t.Fatalf("Delete = %v, %v", ok, err)
+	}
+	if ok, _ := s.Delete("warm"); ok {
+		t.Error("Delete of a missing scene reported true")
+	}
+	s, _ = Open(path)
+	if _, ok := s.Get("warm"); ok {
+		t.Error("deleted scene came back after reopen")
+	}
+}
+
+func TestStore_PutRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := Open(filepath.Join(dir, "missing", File))
+	err := s.Put(Scene{Name: "warm", Kind: KindState})
+	if err == nil {
+		t.Fatal("Put into a missing directory succeeded")
+	}
+	if _, ok := s.Get("warm"); ok {
+		t.Error("failed Put left the scene in the store")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Put created the directory")
+	}
+}
+
+func TestScene_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		scene Scene
+		ok    bool
+	}{
+		{"state", Scene{Name: "a", Kind: KindState, State: map[string]float64{"x": 1}}, true},
+		{"dmx", Scene{Name: "a", Kind: KindDMX, DMX: map[int][]int{1: {0}}}, true},
+		{"empty name", Scene{Kind: KindState}, false},
+		{"slash", Scene{Name: "a/b", Kind: KindState}, false},
+		{"out of range", Scene{Name: "a", Kind: KindState, State: map[string]float64{"x": 1.5}}, false},
+		{"too many channels", Scene{Name: "a", Kind: KindDMX, DMX: map[int][]int{1: make([]int, 513)}}, false},
+		{"unknown kind", Scene{Name: "a", Kind: "cue"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.scene.Validate(); (err == nil) != tt.ok {
+				t.Errorf("Validate() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
@@ -13,6 +13,9 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/dmx"
+	"github.com/footgunz/penumbra/interp"
+	"github.com/footgunz/penumbra/scene"
 )
 
 // --- Messages sent from server goroutines via Program.Send() ---
@@ -27,18 +30,22 @@ type SessionMsg string
 type EmitterSeenMsg struct{}
 
 // UniverseMsg carries the status of a single universe.
+// Delivery is the E1.31 delivery mode and Unicast the unicast destinations.
 type UniverseMsg struct {
-	ID     int
-	Label  string
-	IP     string
-	Online bool
+	ID       int
+	Label    string
+	IP       string
+	Delivery string
+	Unicast  []string
+	Online   bool
 }
 
-// ChannelTarget identifies a DMX channel within a universe.
-type ChannelTarget struct {
-	Universe int
-	Channel  int
-}
+// ChannelTarget identifies a DMX channel within a universe, with its width
+// and value transform.
+type ChannelTarget = config.ChannelTarget
+
+// ParkedMsg carries every parked channel: universe -> channel -> DMX value.
+type ParkedMsg map[int]map[int]int
 
 // ConfigMsg carries the parameter-to-DMX-channel mapping from server config.
 type ConfigMsg map[string][]ChannelTarget
@@ -49,6 +56,9 @@ type EmitterTimeoutsMsg struct {
 	DisconnectTimeout time.Duration
 }
 
+// FailsafeMsg carries the emitter-loss failsafe's action and state.
+type FailsafeMsg scene.FailsafeStatus
+
 // LogMsg carries a single log line.
 type LogMsg string
 
@@ -69,9 +79,11 @@ const (
 )
 
 type universeInfo struct {
-	label  string
-	ip     string
-	online bool
+	label    string
+	ip       string
+	delivery string
+	unicast  []string
+	online   bool
 }
 
 // marquee returns s truncated to maxWidth. If s is longer, it scrolls
@@ -90,34 +102,58 @@ func marquee(s string, maxWidth, tick int) string {
 // BlackoutFuncs groups the blackout-related functions the TUI needs.
 type BlackoutFuncs struct {
 	IsActive func() bool // polls current state — atomic, no blocking
-	Trigger  func()      // activates blackout
+	Trigger  func()      // activates blackout, fading out
+	EStop    func()      // activates blackout instantly, cutting any fade
 	Reset    func()      // clears blackout
 }
 
+// MasterFuncs groups the master-level functions the TUI needs.
+type MasterFuncs struct {
+	Levels func() (grand float64, submasters map[string]float64)
+	Set    func(submaster string, level float64) // "" sets the grand master
+}
+
+// CueFuncs groups the cue-list functions the TUI needs.
+type CueFuncs struct {
+	Status  func() map[string]scene.CueStatus
+	Command func(list, command string) // "go", "back" or "pause"
+}
+
+// masterStep is how far one [ or ] press moves a master.
+const masterStep = 0.05
+
 // Model is the bubbletea model for the Penumbra TUI.
 type Model struct {
-	params    map[string]float64
-	configMap map[string][]ChannelTarget
-	filter    textinput.Model
-	sessionID string
-	tick      int
+	params            map[string]float64
+	configMap         map[string][]ChannelTarget
+	parked            ParkedMsg
+	filter            textinput.Model
+	sessionID         string
+	tick              int
 	emitterLastSeen   time.Time
 	idleTimeout       time.Duration
 	disconnectTimeout time.Duration
 	bo                BlackoutFuncs
-	startTime    time.Time
-	universes    map[int]universeInfo
-	logLines     []string
-	logViewport  viewport.Model
-	focus        focus
-	width        int
-	height       int
-	ready        bool
-	quitting     bool
+	masters           MasterFuncs
+	master            string // selected master: "" for the grand master, else a submaster name
+	cues              CueFuncs
+	cueList           string // selected cue list
+	failsafe          FailsafeMsg
+	emitters          func() []interp.EmitterStatus
+	startTime         time.Time
+	universes         map[int]universeInfo
+	logLines          []string
+	logViewport       viewport.Model
+	focus             focus
+	width             int
+	height            int
+	ready             bool
+	quitting          bool
 }
 
-// New creates a Model ready for tea.NewProgram.
-func New(bo BlackoutFuncs) Model {
+// New creates a Model ready for tea.NewProgram. emitters polls the emitters
+// being played back.
+func New(bo BlackoutFuncs, masters MasterFuncs, cues CueFuncs, emitters func() []interp.EmitterStatus) Model {
 	ti := textinput.New()
 	ti.Placeholder = "type to filter..."
 	ti.Prompt = "/ "
@@ -131,6 +167,9 @@ func New(bo BlackoutFuncs) Model {
 		idleTimeout:       5 * time.Second,
 		disconnectTimeout: 3600 * time.Second,
 		bo:                bo,
+		masters:           masters,
+		cues:              cues,
+		emitters:          emitters,
 		universes:         make(map[int]universeInfo),
 		logLines:          make([]string, 0, 128),
 		focus:             focusParams,
@@ -183,10 +222,56 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 		case "!":
-			if m.bo.Trigger != nil {
+			// A second press during blackout cuts a fade that is still running.
+			if m.bo.IsActive != nil && m.bo.IsActive() {
+				if m.bo.EStop != nil {
+					m.bo.EStop()
+				}
+			} else if m.bo.Trigger != nil {
 				m.bo.Trigger()
 			}
 			return m, nil
+		case "[", "]":
+			if m.masters.Levels != nil && m.masters.Set != nil {
+				step := masterStep
+				if msg.String() == "[" {
+					step = -step
+				}
+				level := m.masterLevel() + step
+				m.masters.Set(m.master, math.Round(math.Max(0, math.Min(1, level))/masterStep)*masterStep)
+			}
+			return m, nil
+		case "{", "}":
+			names := m.masterNames()
+			i := sort.SearchStrings(names, m.master)
+			if msg.String() == "{" {
+				i += len(names) - 1
+			} else {
+				i++
+			}
+			m.master = names[i%len(names)]
+			return m, nil
+		case ">", "<", "|":
+			list := m.selectedCueList()
+			if list == "" || m.cues.Command == nil {
+				return m, nil
+			}
+			command := map[string]string{">": scene.CommandGo, "<": scene.CommandBack, "|": scene.CommandPause}[msg.String()]
+			m.cues.Command(list, command)
+			return m, nil
+		case "(", ")":
+			names := m.cueListNames()
+			if len(names) == 0 {
+				return m, nil
+			}
+			i := sort.SearchStrings(names, m.selectedCueList())
+			if msg.String() == "(" {
+				i += len(names) - 1
+			} else {
+				i++
+			}
+			m.cueList = names[i%len(names)]
+			return m, nil
 		case "esc":
 			if m.bo.IsActive != nil && m.bo.IsActive() {
 				if m.bo.Reset != nil {
@@ -220,6 +305,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.configMap = map[string][]ChannelTarget(msg)
 		return m, nil
 
+	case ParkedMsg:
+		m.parked = msg
+		return m, nil
+
 	case ParamUpdateMsg:
 		for k, v := range msg {
 			m.params[k] = v
@@ -246,12 +335,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case UniverseMsg:
 		m.universes[msg.ID] = universeInfo{
-			label:  msg.Label,
-			ip:     msg.IP,
-			online: msg.Online,
+			label:    msg.Label,
+			ip:       msg.IP,
+			delivery: msg.Delivery,
+			unicast:  msg.Unicast,
+			online:   msg.Online,
 		}
 		return m, nil
 
+	case FailsafeMsg:
+		m.failsafe = msg
+		return m, nil
+
 	case LogMsg:
 		m.logLines = append(m.logLines, string(msg))
 		if len(m.logLines) > 1000 {
@@ -330,6 +425,7 @@ type channelEntry struct {
 	param   string
 	dmx     int
 	value   float64
+	parked  bool
 }
 
 func (m Model) channelsForUniverse(uid int, filter string) []channelEntry {
@@ -344,13 +440,32 @@ func (m Model) channelsForUniverse(uid int, filter string) []channelEntry {
 				!strings.Contains(strconv.Itoa(t.Channel), filter) {
 				continue
 			}
-			v := math.Max(0, math.Min(1, m.params[param]))
-			entries = append(entries, channelEntry{
-				channel: t.Channel,
-				param:   param,
-				dmx:     int(math.Round(v * 255)),
-				value:   v,
-			})
+			v := dmx.Transform(t, m.params[param])
+			for _, cv := range dmx.TargetValues(t, m.params[param]) {
+				name := param
+				if cv.Fine {
+					name += " (fine)"
+				}
+				entries = append(entries, channelEntry{
+					channel: cv.Channel,
+					param:   name,
+					dmx:     int(cv.Value),
+					value:   v,
+				})
+			}
+		}
+	}
+	// Parked channels override whatever drives them; unmapped ones get a row.
+	for ch, v := range m.parked[uid] {
+		found := false
+		for i := range entries {
+			if entries[i].channel == ch {
+				entries[i].dmx, entries[i].value, entries[i].parked = v, float64(v)/255, true
+				found = true
+			}
+		}
+		if !found && (filter == "" || strings.Contains(strconv.Itoa(ch), filter) || strings.Contains("parked", filter)) {
+			entries = append(entries, channelEntry{channel: ch, param: "(parked)", dmx: v, value: float64(v) / 255, parked: true})
 		}
 	}
 	sort.Slice(entries, func(i, j int) bool { return entries[i].channel < entries[j].channel })
@@ -405,11 +520,56 @@ func (m Model) View() string {
 		sess = marquee(sess, 12, m.tick)
 	}
 
-	b.WriteString(fmt.Sprintf(" Emitter %s  Universes %s  Uptime %s  Session %s\n",
+	master := m.master
+	if master == "" {
+		master = "GM"
+	}
+	level := m.masterLevel()
+	masterStyle := dimStyle
+	if level < 1 {
+		masterStyle = warnStyle
+	}
+
+	b.WriteString(fmt.Sprintf(" Emitter %s  Universes %s  Uptime %s  Session %s  Master %s",
 		emitter,
 		uCountStyle.Render(fmt.Sprintf("%d/%d", online, total)),
 		dimStyle.Render(up.String()),
-		headerStyle.Render(sess)))
+		headerStyle.Render(sess),
+		masterStyle.Render(fmt.Sprintf("%s %d%%", master, int(math.Round(level*100))))))
+	if list := m.selectedCueList(); list != "" {
+		st := m.cues.Status()[list]
+		cue := fmt.Sprintf("%s %d/%d", list, st.Cue, st.Count)
+		if st.Label != "" {
+			cue += " " + marquee(st.Label, 16, m.tick)
+		}
+		cueStyle := dimStyle
+		switch {
+		case st.Paused:
+			cue += " ‖"
+			cueStyle = warnStyle
+		case st.Running:
+			cueStyle = okStyle
+		}
+		b.WriteString("  Cue " + cueStyle.Render(cue))
+	}
+	if m.failsafe.Action != "" {
+		failsafe := m.failsafe.Action
+		if m.failsafe.Scene != "" {
+			failsafe += " " + m.failsafe.Scene
+		}
+		if m.failsafe.Active {
+			b.WriteString("  Failsafe " + warnStyle.Render(failsafe+" ACTIVE"))
+		} else {
+			b.WriteString("  Failsafe " + dimStyle.Render(failsafe))
+		}
+	}
+	b.WriteByte('\n')
+
+	emitters := m.emitterLine()
+	if emitters != "" {
+		b.WriteString(emitters)
+		b.WriteByte('\n')
+	}
 
 	blackout := m.bo.IsActive != nil && m.bo.IsActive()
 	if blackout {
@@ -445,6 +605,9 @@ func (m Model) View() string {
 	if blackout {
 		fixed++
 	}
+	if emitters != "" {
+		fixed++
+	}
 	mainLines := m.height - fixed - logH
 	if mainLines < 3 {
 		mainLines = 3
@@ -473,14 +636,50 @@ func (m Model) View() string {
 	// ── Help ──
 	b.WriteByte('\n')
 	if blackout {
-		b.WriteString(dimStyle.Render(" esc reset blackout  ctrl+c quit"))
+		b.WriteString(dimStyle.Render(" ! cut fade  esc reset blackout  ctrl+c quit"))
 	} else {
-		b.WriteString(dimStyle.Render(" tab/shift+tab navigate  / filter  ! blackout  esc back  ctrl+c quit"))
+		b.WriteString(dimStyle.Render(" tab/shift+tab navigate  / filter  [ ] master  { } select master  > < | cue go/back/pause  ( ) select cues  ! blackout  esc back  ctrl+c quit"))
 	}
 
 	return b.String()
 }
 
+// emitterLine lists every emitter with its state, or returns "" when there
+// are none.
+func (m Model) emitterLine() string {
+	if m.emitters == nil {
+		return ""
+	}
+	list := m.emitters()
+	if len(list) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(" Emitters")
+	for _, e := range list {
+		name := e.Label
+		if name == "" {
+			name = e.Source
+		}
+		if e.Namespace != "" {
+			name += " [" + e.Namespace + "]"
+		}
+		style := okStyle
+		switch e.State {
+		case config.EmitterIdle.String():
+			style = warnStyle
+		case config.EmitterDisconnected.String():
+			style = errStyle
+		}
+		line := fmt.Sprintf("%s %s %d", name, marquee(e.SessionID, 8, m.tick), e.Parameters)
+		if dropped := e.Late + e.Stale; dropped > 0 {
+			line += fmt.Sprintf(" ↓%d", dropped)
+		}
+		b.WriteString("  " + style.Render(line))
+	}
+	return b.String()
+}
+
 func (m Model) viewParams(b *strings.Builder, maxLines int) {
 	filter := strings.ToLower(m.filter.Value())
 	type entry struct {
@@ -566,6 +765,13 @@ func (m Model) viewUniverses(b *strings.Builder, maxLines int) {
 		if u.ip != "" {
 			b.WriteString(dimStyle.Render(fmt.Sprintf("  %s", u.ip)))
 		}
+		if u.delivery != "" {
+			delivery := u.delivery
+			if len(u.unicast) > 0 {
+				delivery += " → " + strings.Join(u.unicast, ", ")
+			}
+			b.WriteString(dimStyle.Render(fmt.Sprintf("  [%s]", delivery)))
+		}
 		b.WriteByte('\n')
 		lines++
 		shown++
@@ -584,8 +790,12 @@ func (m Model) viewUniverses(b *strings.Builder, maxLines int) {
 			filled := int(float64(chBarW) * ch.value)
 			bar := barFullStyle.Render(strings.Repeat("█", filled)) +
 				barDimStyle.Render(strings.Repeat("░", chBarW-filled))
+			value := dimStyle.Render(fmt.Sprintf("%3d", ch.dmx))
+			if ch.parked {
+				value = warnStyle.Render(fmt.Sprintf("%3d P", ch.dmx))
+			}
 			b.WriteString(fmt.Sprintf("     %3d  %-*s %s %s\n",
-				ch.channel, nameW, pName, bar, dimStyle.Render(fmt.Sprintf("%3d", ch.dmx))))
+				ch.channel, nameW, pName, bar, value))
 			lines++
 		}
 		if len(channels) == 0 {
@@ -603,3 +813,58 @@ func (m Model) viewUniverses(b *strings.Builder, maxLines int) {
 		b.WriteByte('\n')
 	}
 }
+
+// masterNames returns the selectable masters: "" (the grand master) followed
+// by the submasters in name order.
+func (m Model) masterNames() []string {
+	names := []string{""}
+	if m.masters.Levels != nil {
+		_, subs := m.masters.Levels()
+		for name := range subs {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cueListNames returns the configured cue lists in name order.
+func (m Model) cueListNames() []string {
+	if m.cues.Status == nil {
+		return nil
+	}
+	var names []string
+	for name := range m.cues.Status() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectedCueList returns the selected cue list, defaulting to the first,
+// or "" if there are none.
+func (m Model) selectedCueList() string {
+	names := m.cueListNames()
+	if len(names) == 0 {
+		return ""
+	}
+	if i := sort.SearchStrings(names, m.cueList); i < len(names) && names[i] == m.cueList {
+		return m.cueList
+	}
+	return names[0]
+}
+
+// masterLevel returns the level of the selected master, 1 if unknown.
+func (m Model) masterLevel() float64 {
+	if m.masters.Levels == nil {
+		return 1
+	}
+	grand, subs := m.masters.Levels()
+	if m.master == "" {
+		return grand
+	}
+	if level, ok := subs[m.master]; ok {
+		return level
+	}
+	return 1
+}
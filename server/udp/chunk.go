@@ -0,0 +1,96 @@
+package udp
+
+import (
+	"log"
+	"maps"
+	"time"
+)
+
+const (
+	// reassemblyTimeout is how long the chunks of a frame are kept waiting
+	// for the rest; an emitter sends them back to back.
+	reassemblyTimeout = 500 * time.Millisecond
+	// maxChunks bounds the datagrams one frame may span.
+	maxChunks = 256
+)
+
+// frameKey identifies a chunked frame.
+type frameKey struct {
+	source  string
+	session string
+	frame   uint32
+}
+
+// frame collects the chunks of one packet.
+type frame struct {
+	pkt      StatePacket // the first chunk, with the state gathered so far
+	received []bool
+	missing  int
+	started  time.Time
+}
+
+// reassemble returns pkt as is if it isn't chunked. A chunk is held until
+// the rest of its frame arrives, and the reassembled packet is returned
+// with the last one. Frames still incomplete after reassemblyTimeout are
+// discarded.
+func (r *Receiver) reassemble(pkt StatePacket) (StatePacket, bool) {
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire(now)
+	if pkt.Chunks <= 1 {
+		return pkt, true
+	}
+
+	r.stats.Chunks++
+	if pkt.Chunks > maxChunks || pkt.Chunk < 0 || pkt.Chunk >= pkt.Chunks {
+		r.stats.Errors++
+		log.Printf("udp: %s: bad chunk %d of %d", pkt.Source, pkt.Chunk, pkt.Chunks)
+		return pkt, false
+	}
+	key := frameKey{pkt.Source, pkt.SessionID, pkt.Frame}
+	f := r.frames[key]
+	if f == nil {
+		f = &frame{
+			pkt:      pkt,
+			received: make([]bool, pkt.Chunks),
+			missing:  pkt.Chunks,
+			started:  now,
+		}
+		f.pkt.State = make(map[string]float64)
+		r.frames[key] = f
+	}
+	if pkt.Chunks != len(f.received) || f.received[pkt.Chunk] {
+		return pkt, false // inconsistent or duplicate
+	}
+	f.received[pkt.Chunk] = true
+	f.missing--
+	maps.Copy(f.pkt.State, pkt.State)
+	if f.missing > 0 {
+		return pkt, false
+	}
+
+	delete(r.frames, key)
+	out := f.pkt
+	out.Frame, out.Chunk, out.Chunks = 0, 0, 0
+	return out, true
+}
+
+// sweep expires incomplete frames between datagrams.
+func (r *Receiver) sweep() {
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire(now)
+}
+
+// expire discards frames that have waited longer than reassemblyTimeout.
+// Must be called with r.mu held.
+func (r *Receiver) expire(now time.Time) {
+	for key, f := range r.frames {
+		if now.Sub(f.started) > reassemblyTimeout {
+			delete(r.frames, key)
+			r.stats.Incomplete++
+		}
+	}
+}
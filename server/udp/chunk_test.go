@@ -0,0 +1,102 @@
+package udp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// testReceiver returns a receiver on a fake clock.
+func testReceiver() (*Receiver, *time.Time) {
+	r := NewReceiver(0, nil)
+	clock := time.UnixMilli(10_000)
+	r.now = func() time.Time { return clock }
+	return r, &clock
+}
+
+func chunk(frame uint32, i, n int, state map[string]float64) StatePacket {
+	return StatePacket{SessionID: "s", Ts: 1, Source: "10.0.0.5:5000", Frame: frame, Chunk: i, Chunks: n, State: state}
+}
+
+func TestReassemble(t *testing.T) {
+	r, _ := testReceiver()
+	if _, ok := r.reassemble(chunk(1, 1, 3, map[string]float64{"b": 0.2})); ok {
+		t.Fatalf("frame complete after one chunk")
+	}
+	r.reassemble(chunk(1, 0, 3, map[string]float64{"a": 0.1}))
+	if _, ok := r.reassemble(chunk(1, 0, 3, map[string]float64{"a": 0.1})); ok {
+		t.Fatalf("duplicate chunk completed the frame")
+	}
+	pkt, ok := r.reassemble(chunk(1, 2, 3, map[string]float64{"c": 0.3}))
+	if !ok || len(pkt.State) != 3 || pkt.State["a"] != 0.1 || pkt.State["c"] != 0.3 || pkt.Chunks != 0 {
+		t.Fatalf("reassembled: %+v %v", pkt, ok)
+	}
+	if len(r.frames) != 0 {
+		t.Errorf("%d frames left pending", len(r.frames))
+	}
+}
+
+func TestReassemble_Timeout(t *testing.T) {
+	r, clock := testReceiver()
+	r.reassemble(chunk(1, 0, 2, map[string]float64{"a": 0.1}))
+	*clock = clock.Add(reassemblyTimeout + time.Millisecond)
+
+	// Frame 1 is discarded; its last chunk starts a frame of its own that
+	// can't complete.
+	if _, ok := r.reassemble(chunk(1, 1, 2, map[string]float64{"b": 0.2})); ok {
+		t.Fatalf("expired frame completed")
+	}
+	if _, ok := r.reassemble(chunk(2, 5, 2, nil)); ok {
+		t.Fatalf("chunk index past the count accepted")
+	}
+	if pkt, ok := r.reassemble(StatePacket{SessionID: "s", State: map[string]float64{"a": 1}}); !ok || pkt.State["a"] != 1 {
+		t.Fatalf("unchunked packet: %+v %v", pkt, ok)
+	}
+	if st := r.Stats(); st.Chunks != 3 || st.Incomplete != 1 || st.Errors != 1 {
+		t.Errorf("Stats() = %+v, want 3 chunks, 1 incomplete, 1 error", st)
+	}
+}
+
+func TestServe_ExpiresWithoutTraffic(t *testing.T) {
+	r := NewReceiver(0, func(StatePacket) {})
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		r.serve(conn)
+		close(done)
+	}()
+	defer func() {
+		conn.Close()
+		<-done
+	}()
+
+	out, err := net.DialUDP("udp", nil, conn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	data, err := msgpack.Marshal(chunk(1, 0, 2, map[string]float64{"a": 0.1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := out.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rest of the frame never comes, and neither does anything else.
+	deadline := time.Now().Add(4 * reassemblyTimeout)
+	for r.Stats().Incomplete == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("frame not expired without traffic: %+v", r.Stats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st := r.Stats(); st.Chunks != 1 {
+		t.Errorf("Stats() = %+v, want 1 chunk", st)
+	}
+}
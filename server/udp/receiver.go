@@ -1,29 +1,89 @@
 package udp
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"sync"
+	"time"
 
 	"github.com/vmihailenco/msgpack/v5"
 )
 
 // StatePacket is the wire format received from an emitter (M4L, fake-emitter, etc.).
+// Source is the sender's "ip:port", filled in on receipt.
+//
+// Seq and Delta are optional. A packet without a Seq carries the full state.
+// With a Seq, which counts up from 1 per packet (skipping 0 on wrap), a
+// Delta packet carries only the parameters that changed since the previous
+// packet, and any other packet is a keyframe carrying the full state.
+//
+// Frame, Chunk and Chunks split one packet too large for a datagram: each
+// of the Chunks datagrams carries the same Frame id, session, ts, seq and
+// delta flag, its 0-based Chunk index and a share of the state.
 type StatePacket struct {
 	SessionID string             `msgpack:"session_id"`
 	Ts        int64              `msgpack:"ts"`
 	State     map[string]float64 `msgpack:"state"`
+	Seq       uint32             `msgpack:"seq,omitempty"`
+	Delta     bool               `msgpack:"delta,omitempty"`
+	Frame     uint32             `msgpack:"frame,omitempty"`
+	Chunk     int                `msgpack:"chunk,omitempty"`
+	Chunks    int                `msgpack:"chunks,omitempty"`
+	Source    string             `msgpack:"-"`
 }
 
-// Receiver reads UDP datagrams and decodes them as StatePackets.
+// Receiver reads UDP datagrams and decodes them as StatePackets. Chunked
+// packets are reassembled and delta packets applied to their source's last
+// state, so the handler always sees one full state per packet.
 type Receiver struct {
 	port    int
 	handler func(StatePacket)
+	seen    func(StatePacket)
+	now     func() time.Time
+
+	mu      sync.Mutex
+	frames  map[frameKey]*frame
+	streams map[string]*stream // keyed by source address
+	stats   Stats
+}
+
+// Stats counts the packets a Receiver has decoded.
+type Stats struct {
+	Packets    uint64 `json:"packets"`   // after reassembly
+	Keyframes  uint64 `json:"keyframes"` // full-state packets with a seq
+	Deltas     uint64 `json:"deltas"`
+	Resyncs    uint64 `json:"resyncs"`    // sequence gaps that lost the delta chain
+	Dropped    uint64 `json:"dropped"`    // deltas that couldn't be applied
+	Chunks     uint64 `json:"chunks"`     // datagrams carrying part of a frame
+	Incomplete uint64 `json:"incomplete"` // chunked frames discarded after a timeout
+	Errors     uint64 `json:"errors"`     // malformed datagrams
 }
 
 // NewReceiver creates a Receiver that calls handler for each decoded packet.
 func NewReceiver(port int, handler func(StatePacket)) *Receiver {
-	return &Receiver{port: port, handler: handler}
+	return &Receiver{
+		port:    port,
+		handler: handler,
+		now:     time.Now,
+		frames:  make(map[frameKey]*frame),
+		streams: make(map[string]*stream),
+	}
+}
+
+// SetOnSeen registers a callback for every packet received, called before
+// its delta is applied, so a delta dropped for a broken chain still marks
+// its source as seen.
+func (r *Receiver) SetOnSeen(fn func(StatePacket)) {
+	r.seen = fn
+}
+
+// Stats returns the receiver's packet counters.
+func (r *Receiver) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
 }
 
 // Listen opens a UDP socket and blocks, decoding packets and calling the handler.
@@ -39,19 +99,51 @@ func (r *Receiver) Listen() {
 	}
 	defer conn.Close()
 	log.Printf("udp: listening on :%d", r.port)
+	r.serve(conn)
+}
 
+// serve reads packets from conn until it is closed. Reads time out every
+// reassemblyTimeout so incomplete frames expire even when nothing else
+// arrives.
+func (r *Receiver) serve(conn *net.UDPConn) {
 	buf := make([]byte, 65536)
 	for {
-		n, _, err := conn.ReadFromUDP(buf)
+		conn.SetReadDeadline(time.Now().Add(reassemblyTimeout))
+		n, from, err := conn.ReadFromUDP(buf)
 		if err != nil {
-			log.Printf("udp: read error: %v", err)
+			var ne net.Error
+			switch {
+			case errors.As(err, &ne) && ne.Timeout():
+				r.sweep()
+			case errors.Is(err, net.ErrClosed):
+				return
+			default:
+				log.Printf("udp: read error: %v", err)
+			}
 			continue
 		}
 		var pkt StatePacket
 		if err := msgpack.Unmarshal(buf[:n], &pkt); err != nil {
+			r.mu.Lock()
+			r.stats.Errors++
+			r.mu.Unlock()
 			log.Printf("udp: decode error: %v", err)
 			continue
 		}
+		pkt.Source = from.String()
+		if pkt, ok := r.reassemble(pkt); ok {
+			r.receive(pkt)
+		}
+	}
+}
+
+// receive passes a whole packet to the seen callback, then to the handler
+// unless its delta can't be applied.
+func (r *Receiver) receive(pkt StatePacket) {
+	if r.seen != nil {
+		r.seen(pkt)
+	}
+	if pkt, ok := r.expand(pkt); ok {
 		r.handler(pkt)
 	}
 }
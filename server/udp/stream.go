@@ -0,0 +1,76 @@
+package udp
+
+import (
+	"log"
+	"maps"
+)
+
+// stream is the delta chain of one source's session.
+type stream struct {
+	session string
+	seq     uint32             // seq of the last packet applied
+	state   map[string]float64 // full state as of seq
+	synced  bool               // a keyframe has been applied since the last gap
+}
+
+// expand returns pkt with its full state, applying a delta to its source's
+// stream. A delta that doesn't follow the last packet applied can't be, so
+// it is dropped, and the source's deltas are dropped until its next
+// keyframe. A keyframe older than the last packet applied is passed on
+// without touching the stream; playback drops it by ts.
+func (r *Receiver) expand(pkt StatePacket) (StatePacket, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Packets++
+	if pkt.Seq == 0 {
+		delete(r.streams, pkt.Source)
+		return pkt, true
+	}
+
+	s := r.streams[pkt.Source]
+	if s == nil || s.session != pkt.SessionID {
+		s = &stream{session: pkt.SessionID}
+		r.streams[pkt.Source] = s
+	}
+	if !pkt.Delta {
+		r.stats.Keyframes++
+		if !s.synced || after(pkt.Seq, s.seq) {
+			s.seq, s.state, s.synced = pkt.Seq, maps.Clone(pkt.State), true
+		}
+		return pkt, true
+	}
+
+	r.stats.Deltas++
+	switch {
+	case !s.synced:
+		r.stats.Dropped++
+		return pkt, false
+	case pkt.Seq == next(s.seq):
+		s.seq = pkt.Seq
+		maps.Copy(s.state, pkt.State)
+		pkt.State, pkt.Delta = maps.Clone(s.state), false
+		return pkt, true
+	case after(pkt.Seq, s.seq):
+		r.stats.Resyncs++
+		r.stats.Dropped++
+		s.synced = false
+		log.Printf("udp: %s: seq %d after %d, waiting for a keyframe", pkt.Source, pkt.Seq, s.seq)
+		return pkt, false
+	default: // late or duplicate
+		r.stats.Dropped++
+		return pkt, false
+	}
+}
+
+// next returns the seq that follows seq, skipping 0 on wrap.
+func next(seq uint32) uint32 {
+	if seq == ^uint32(0) {
+		return 1
+	}
+	return seq + 1
+}
+
+// after reports whether seq a is later than b, allowing for wrap.
+func after(a, b uint32) bool {
+	return int32(a-b) > 0
+}
@@ -0,0 +1,70 @@
+package udp
+
+import "testing"
+
+func TestExpand_Legacy(t *testing.T) {
+	r := NewReceiver(0, nil)
+	pkt, ok := r.expand(StatePacket{SessionID: "s", Ts: 1, State: map[string]float64{"dim": 1}})
+	if !ok || pkt.State["dim"] != 1 {
+		t.Fatalf("full-state packet: %+v %v", pkt, ok)
+	}
+}
+
+func TestExpand_Deltas(t *testing.T) {
+	r := NewReceiver(0, nil)
+	src := "10.0.0.5:5000"
+	delta := func(seq uint32, state map[string]float64) (StatePacket, bool) {
+		return r.expand(StatePacket{SessionID: "s", Seq: seq, Delta: true, State: state, Source: src})
+	}
+
+	if _, ok := delta(1, map[string]float64{"dim": 1}); ok {
+		t.Fatalf("delta before any keyframe was applied")
+	}
+	r.expand(StatePacket{SessionID: "s", Seq: 2, State: map[string]float64{"dim": 0.5, "hue": 0.2}, Source: src})
+	pkt, ok := delta(3, map[string]float64{"hue": 0.4})
+	if !ok || pkt.Delta || pkt.State["dim"] != 0.5 || pkt.State["hue"] != 0.4 {
+		t.Fatalf("delta after keyframe: %+v %v, want the full state", pkt, ok)
+	}
+	if _, ok := delta(3, map[string]float64{"hue": 0.1}); ok {
+		t.Errorf("duplicate delta was applied")
+	}
+
+	// Seq 4 is lost: the chain is broken until the next keyframe.
+	if _, ok := delta(5, map[string]float64{"dim": 0}); ok {
+		t.Errorf("delta after a gap was applied")
+	}
+	if _, ok := delta(6, map[string]float64{"dim": 0}); ok {
+		t.Errorf("delta while resyncing was applied")
+	}
+	r.expand(StatePacket{SessionID: "s", Seq: 7, State: map[string]float64{"dim": 0.1, "hue": 0.4}, Source: src})
+	if pkt, ok := delta(8, map[string]float64{"dim": 0.2}); !ok || pkt.State["hue"] != 0.4 {
+		t.Errorf("delta after resync: %+v %v", pkt, ok)
+	}
+
+	want := Stats{Packets: 8, Keyframes: 2, Deltas: 6, Resyncs: 1, Dropped: 4}
+	if got := r.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestExpand_SeqWrap(t *testing.T) {
+	r := NewReceiver(0, nil)
+	r.expand(StatePacket{SessionID: "s", Seq: ^uint32(0), State: map[string]float64{"dim": 0}})
+	if _, ok := r.expand(StatePacket{SessionID: "s", Seq: 1, Delta: true, State: map[string]float64{"dim": 1}}); !ok {
+		t.Errorf("delta after seq wrap was dropped")
+	}
+}
+
+func TestReceive_SeenBeforeDrop(t *testing.T) {
+	var seen, handled int
+	r := NewReceiver(0, func(StatePacket) { handled++ })
+	r.SetOnSeen(func(StatePacket) { seen++ })
+	src := "10.0.0.5:5000"
+
+	r.receive(StatePacket{SessionID: "s", Seq: 1, State: map[string]float64{"dim": 1}, Source: src})
+	r.receive(StatePacket{SessionID: "s", Seq: 3, Delta: true, State: map[string]float64{"dim": 0}, Source: src})
+	r.receive(StatePacket{SessionID: "s", Seq: 2, Delta: true, State: map[string]float64{"dim": 0}, Source: src})
+	if seen != 3 || handled != 1 {
+		t.Errorf("seen %d, handled %d packets, want 3 seen and 1 handled", seen, handled)
+	}
+}
@@ -3,7 +3,6 @@ package ws
 import (
 	"encoding/json"
 	"log"
-	"math"
 	"net/http"
 	"sort"
 	"sync"
@@ -11,6 +10,13 @@ import (
 	"time"
 
 	"github.com/footgunz/penumbra/config"
+	"github.com/footgunz/penumbra/dmx"
+	"github.com/footgunz/penumbra/e131"
+	"github.com/footgunz/penumbra/interp"
+	"github.com/footgunz/penumbra/osc"
+	"github.com/footgunz/penumbra/output"
+	"github.com/footgunz/penumbra/scene"
+	"github.com/footgunz/penumbra/udp"
 	"github.com/gorilla/websocket"
 )
 
@@ -43,7 +49,20 @@ type Hub struct {
 	// Blackout: atomic flag. When set, state/diff messages are still processed
 	// internally but not relayed to WS clients. Status messages always flow.
 	blackout   atomic.Bool
-	onBlackout func() // one-shot callback for E1.31 blackout scene dispatch
+	onBlackout func(fade time.Duration) // one-shot callback for E1.31 blackout scene dispatch
+	onReset    func(fade time.Duration) // one-shot callback to restore live output after blackout
+
+	outputStats   func() []output.Stats // per-driver counters for the status message
+	receiverStats func() udp.Stats      // emitter packet counters
+	oscStats      func() osc.Stats      // OSC input counters
+	inputSources  func() []e131.Source  // active external sACN sources
+	parked        func() []output.ParkedChannel
+	masters       *output.Masters
+	heldScene     func() string // name of the held scene, if any
+	cues          *scene.Cues
+	failsafe      func() scene.FailsafeStatus
+	emitters      func() []interp.EmitterStatus
+	fixtures      config.FixtureResolver // channel names for colour parameters
 }
 
 type client struct {
@@ -169,49 +188,193 @@ func emitterState(lastSeen time.Time, cfg *config.Config) config.EmitterState {
 	if lastSeen.IsZero() {
 		return config.EmitterDisconnected
 	}
-	elapsed := time.Since(lastSeen)
-	if elapsed >= time.Duration(cfg.Emitter.DisconnectTimeoutSec)*time.Second {
-		return config.EmitterDisconnected
-	}
-	if elapsed >= time.Duration(cfg.Emitter.IdleTimeoutSec)*time.Second {
-		return config.EmitterIdle
-	}
-	return config.EmitterConnected
+	return cfg.Emitter.State(time.Since(lastSeen))
 }
 
 // SetOnBlackout registers a function called once when blackout is activated
-// (e.g. to dispatch the blackout scene to E1.31).
-func (h *Hub) SetOnBlackout(fn func()) {
+// (e.g. to dispatch the blackout scene to E1.31), with the time to fade over.
+// An e-stop calls it again with 0 to cut a fade that is still running.
+func (h *Hub) SetOnBlackout(fn func(fade time.Duration)) {
 	h.onBlackout = fn
 }
 
-// Blackout enters blackout mode. State/diff messages stop flowing to WS
-// clients. Status broadcasts continue so UIs can show the blackout banner.
+// SetOnReset registers a function called once when blackout is cleared
+// (e.g. to re-dispatch the current emitter state to E1.31), with the time
+// to fade over.
+func (h *Hub) SetOnReset(fn func(fade time.Duration)) {
+	h.onReset = fn
+}
+
+// SetOutputStats registers the source of per-driver output counters
+// reported in the status message.
+func (h *Hub) SetOutputStats(fn func() []output.Stats) {
+	h.outputStats = fn
+}
+
+// SetParked registers the source of parked channels reported per universe
+// in the status message.
+func (h *Hub) SetParked(fn func() []output.ParkedChannel) {
+	h.parked = fn
+}
+
+// SetMasters registers the masters that WS clients control and the status
+// message reports.
+func (h *Hub) SetMasters(m *output.Masters) {
+	h.masters = m
+}
+
+// SetHeldScene registers the source of the held scene name reported in the
+// status message.
+func (h *Hub) SetHeldScene(fn func() string) {
+	h.heldScene = fn
+}
+
+// SetCues registers the cue playback that WS clients control and the status
+// message reports.
+func (h *Hub) SetCues(c *scene.Cues) {
+	h.cues = c
+}
+
+// SetFailsafe registers the source of the failsafe state reported in the
+// status message.
+func (h *Hub) SetFailsafe(fn func() scene.FailsafeStatus) {
+	h.failsafe = fn
+}
+
+// SetReceiverStats registers the source of the emitter packet counters
+// reported in the status message.
+func (h *Hub) SetReceiverStats(fn func() udp.Stats) {
+	h.receiverStats = fn
+}
+
+// SetOSCStats registers the source of the OSC input counters reported in
+// the status message.
+func (h *Hub) SetOSCStats(fn func() osc.Stats) {
+	h.oscStats = fn
+}
+
+// SetEmitters registers the source of the per-emitter state reported in the
+// status message.
+func (h *Hub) SetEmitters(fn func() []interp.EmitterStatus) {
+	h.emitters = fn
+}
+
+// SetFixtures registers the fixture lookup used to report the channels that
+// colour parameters drive.
+func (h *Hub) SetFixtures(resolve config.FixtureResolver) {
+	h.fixtures = resolve
+}
+
+// SetInputSources registers the source of the active sACN input sources
+// reported in the status message.
+func (h *Hub) SetInputSources(fn func() []e131.Source) {
+	h.inputSources = fn
+}
+
+// Blackout enters blackout mode, fading out over the configured blackout
+// fade time. State/diff messages stop flowing to WS clients. Status
+// broadcasts continue so UIs can show the blackout banner.
 // The atomic swap is immediate; side effects (E1.31 dispatch, log, status
 // broadcast) run in a goroutine so callers never block.
 func (h *Hub) Blackout() {
 	if h.blackout.CompareAndSwap(false, true) {
+		fade := h.cfg.Fades.Blackout()
 		go func() {
 			if h.onBlackout != nil {
-				h.onBlackout()
+				h.onBlackout(fade)
 			}
-			log.Printf("BLACKOUT activated")
+			log.Printf("BLACKOUT activated (fade %v)", fade)
 			h.BroadcastStatus()
 		}()
 	}
 }
 
-// Reset exits blackout mode and resumes normal message relay.
+// EStop enters blackout mode without fading. If a blackout is already
+// fading out, it cuts straight to the end.
+func (h *Hub) EStop() {
+	h.blackout.Store(true)
+	go func() {
+		if h.onBlackout != nil {
+			h.onBlackout(0)
+		}
+		log.Printf("E-STOP activated")
+		h.BroadcastStatus()
+	}()
+}
+
+// Reset exits blackout mode and resumes normal message relay, fading back
+// in over the configured reset fade time.
 // Same non-blocking pattern as Blackout.
 func (h *Hub) Reset() {
 	if h.blackout.CompareAndSwap(true, false) {
+		fade := h.cfg.Fades.Reset()
 		go func() {
+			if h.onReset != nil {
+				h.onReset(fade)
+			}
 			log.Printf("BLACKOUT reset — resuming normal operation")
 			h.BroadcastStatus()
 		}()
 	}
 }
 
+// setMaster sets the named sub-master, or the grand master if name is empty,
+// and broadcasts the new levels. Requests POST /api/masters would reject are
+// answered with an error message to c.
+func (h *Hub) setMaster(c *client, name string, level float64) {
+	if h.masters == nil {
+		return
+	}
+	err := h.masters.Validate(name, level)
+	if err == nil {
+		if name == "" {
+			h.masters.SetGrand(level)
+		} else {
+			err = h.masters.SetSubmaster(name, level)
+		}
+	}
+	if err != nil {
+		log.Printf("ws: master: %v", err)
+		h.sendError(c, "master", err)
+		return
+	}
+	h.BroadcastStatus()
+}
+
+// sendError tells c that a command it sent was rejected.
+func (h *Hub) sendError(c *client, command string, err error) {
+	msg := struct {
+		Type    string `json:"type"`
+		Command string `json:"command"`
+		Error   string `json:"error"`
+	}{"error", command, err.Error()}
+	data, merr := json.Marshal(msg)
+	if merr != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; !ok {
+		return // dropped, its send channel is closed
+	}
+	select {
+	case c.send <- data:
+	default:
+	}
+}
+
+// cueCommand runs a cue command and broadcasts the new positions.
+func (h *Hub) cueCommand(list, command string) {
+	if h.cues == nil {
+		return
+	}
+	if err := h.cues.Command(list, command); err != nil {
+		log.Printf("ws: cue %s %s: %v", list, command, err)
+		return
+	}
+	h.BroadcastStatus()
+}
+
 // IsBlackout returns true if the server is in blackout mode.
 func (h *Hub) IsBlackout() bool {
 	return h.blackout.Load()
@@ -250,26 +413,65 @@ func (h *Hub) buildStatusMessage() []byte {
 		Channel int    `json:"channel"`
 		Param   string `json:"param"`
 		Value   int    `json:"value"` // DMX value 0–255
+		Width   int    `json:"width"` // 8, or 16 for both halves of a coarse/fine pair
+		Fine    bool   `json:"fine"`  // fine channel of a 16-bit target
+	}
+	type parkedInfo struct {
+		Channel int `json:"channel"`
+		Value   int `json:"value"` // DMX value 0–255
 	}
 	type universeStatus struct {
 		Label    string        `json:"label"`
 		DeviceIP string        `json:"device_ip"`
 		Type     string        `json:"type"`
+		Protocol string        `json:"protocol"`
+		Delivery string        `json:"delivery"`
+		Unicast  []string      `json:"unicast"`
 		Online   bool          `json:"online"`
 		Channels []channelInfo `json:"channels"`
+		Parked   []parkedInfo  `json:"parked"`
 	}
 
 	// Build per-universe channel lists from current parameter state.
 	universeChannels := make(map[int][]channelInfo)
 	for paramName, targets := range h.cfg.Parameters {
 		value := lastState[paramName] // 0.0 if not yet received
-		dmx := int(math.Round(math.Max(0, math.Min(1, value)) * 255))
 		for _, t := range targets {
-			universeChannels[t.Universe] = append(universeChannels[t.Universe], channelInfo{
-				Channel: t.Channel,
-				Param:   paramName,
-				Value:   dmx,
-			})
+			width := 8
+			if t.Is16Bit() {
+				width = 16
+			}
+			for _, cv := range dmx.TargetValues(t, value) {
+				universeChannels[t.Universe] = append(universeChannels[t.Universe], channelInfo{
+					Channel: cv.Channel,
+					Param:   paramName,
+					Value:   int(cv.Value),
+					Width:   width,
+					Fine:    cv.Fine,
+				})
+			}
+		}
+	}
+	if h.fixtures != nil {
+		for name, cp := range h.cfg.Colors {
+			p, ok := h.cfg.ColorPatch(cp)
+			if !ok {
+				continue
+			}
+			values := dmx.ColorValues(cp, p, p.ChannelNames(h.fixtures), lastState)
+			for i, cv := range values {
+				width := 8
+				if cv.Fine || (i+1 < len(values) && values[i+1].Fine) {
+					width = 16 // a 16-bit dimmer: coarse then fine
+				}
+				universeChannels[cp.Universe] = append(universeChannels[cp.Universe], channelInfo{
+					Channel: cv.Channel,
+					Param:   name,
+					Value:   int(cv.Value),
+					Width:   width,
+					Fine:    cv.Fine,
+				})
+			}
 		}
 	}
 	for u := range universeChannels {
@@ -278,33 +480,107 @@ func (h *Hub) buildStatusMessage() []byte {
 		})
 	}
 
+	universeParked := make(map[int][]parkedInfo)
+	if h.parked != nil {
+		for _, p := range h.parked() {
+			universeParked[p.Universe] = append(universeParked[p.Universe], parkedInfo{Channel: p.Channel, Value: p.Value})
+		}
+	}
+
 	universes := make(map[int]universeStatus, len(h.cfg.Universes))
 	for id, u := range h.cfg.Universes {
 		channels := universeChannels[id]
 		if channels == nil {
 			channels = []channelInfo{}
 		}
+		unicast := u.UnicastTargets()
+		if unicast == nil {
+			unicast = []string{}
+		}
+		parked := universeParked[id]
+		if parked == nil {
+			parked = []parkedInfo{}
+		}
 		universes[id] = universeStatus{
 			Label:    u.Label,
 			DeviceIP: u.DeviceIP,
 			Type:     u.Type,
+			Protocol: u.OutputProtocol(),
+			Delivery: u.DeliveryMode(),
+			Unicast:  unicast,
 			Online:   universeOnline[id],
 			Channels: channels,
+			Parked:   parked,
 		}
 	}
 
+	outputs := []output.Stats{}
+	if h.outputStats != nil {
+		outputs = h.outputStats()
+	}
+	inputs := []e131.Source{}
+	if h.inputSources != nil {
+		inputs = h.inputSources()
+	}
+	masters := output.MasterLevels{Grand: 1, Submasters: map[string]float64{}}
+	if h.masters != nil {
+		masters = h.masters.Levels()
+	}
+	heldScene := ""
+	if h.heldScene != nil {
+		heldScene = h.heldScene()
+	}
+	cues := map[string]scene.CueStatus{}
+	if h.cues != nil {
+		cues = h.cues.Status()
+	}
+	failsafe := scene.FailsafeStatus{Action: h.cfg.Emitter.Failsafe.Action}
+	if h.failsafe != nil {
+		failsafe = h.failsafe()
+	}
+	var receiver udp.Stats
+	if h.receiverStats != nil {
+		receiver = h.receiverStats()
+	}
+	var oscStats osc.Stats
+	if h.oscStats != nil {
+		oscStats = h.oscStats()
+	}
+	emitters := []interp.EmitterStatus{}
+	if h.emitters != nil {
+		emitters = h.emitters()
+	}
+
 	msg := struct {
-		Type        string                `json:"type"`
-		EmitterState    string                `json:"emitter_state"`
-		EmitterLastSeen int64                 `json:"emitter_last_seen"`
-		Blackout    bool                  `json:"blackout"`
-		Universes   map[int]universeStatus `json:"universes"`
+		Type            string                     `json:"type"`
+		EmitterState    string                     `json:"emitter_state"`
+		EmitterLastSeen int64                      `json:"emitter_last_seen"`
+		Blackout        bool                       `json:"blackout"`
+		Universes       map[int]universeStatus     `json:"universes"`
+		Outputs         []output.Stats             `json:"outputs"`
+		Inputs          []e131.Source              `json:"inputs"`
+		Masters         output.MasterLevels        `json:"masters"`
+		HeldScene       string                     `json:"held_scene"`
+		Cues            map[string]scene.CueStatus `json:"cues"`
+		Failsafe        scene.FailsafeStatus       `json:"failsafe"`
+		Emitters        []interp.EmitterStatus     `json:"emitters"`
+		Receiver        udp.Stats                  `json:"receiver"`
+		OSC             osc.Stats                  `json:"osc"`
 	}{
-		Type:        "status",
+		Type:            "status",
 		EmitterState:    stateStr,
 		EmitterLastSeen: lastSeenMs,
-		Blackout:    h.blackout.Load(),
-		Universes:   universes,
+		Blackout:        h.blackout.Load(),
+		Universes:       universes,
+		Outputs:         outputs,
+		Inputs:          inputs,
+		Masters:         masters,
+		HeldScene:       heldScene,
+		Cues:            cues,
+		Failsafe:        failsafe,
+		Emitters:        emitters,
+		Receiver:        receiver,
+		OSC:             oscStats,
 	}
 	data, _ := json.Marshal(msg)
 	return data
@@ -394,7 +670,11 @@ func (c *client) readPump() {
 			break
 		}
 		var envelope struct {
-			Type string `json:"type"`
+			Type      string  `json:"type"`
+			Submaster string  `json:"submaster"`
+			Level     float64 `json:"level"`
+			List      string  `json:"list"`
+			Command   string  `json:"command"`
 		}
 		if json.Unmarshal(data, &envelope) != nil {
 			continue
@@ -402,6 +682,12 @@ func (c *client) readPump() {
 		switch envelope.Type {
 		case "blackout":
 			c.hub.Blackout()
+		case "estop":
+			c.hub.EStop()
+		case "master":
+			c.hub.setMaster(c, envelope.Submaster, envelope.Level)
+		case "cue":
+			c.hub.cueCommand(envelope.List, envelope.Command)
 		case "reset":
 			c.hub.Reset()
 		}
@@ -10,6 +10,8 @@
 //   go run . --mode stress                  # fast sine sweeps for load testing
 //   go run . --mode scripted --scene scenes/example.json  # replay JSON scene (future)
 //   go run . --target 192.168.1.50:7000     # target a remote server
+//   go run . --delta                        # send deltas with a keyframe every second
+//   go run . --params 5000                  # add bulk parameters, chunked to fit --max-datagram
 
 package main
 
@@ -24,6 +26,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"syscall"
 	"time"
 
@@ -35,6 +38,11 @@ type StatePacket struct {
 	SessionID string             `msgpack:"session_id"`
 	Ts        int64              `msgpack:"ts"`
 	State     map[string]float64 `msgpack:"state"`
+	Seq       uint32             `msgpack:"seq,omitempty"`
+	Delta     bool               `msgpack:"delta,omitempty"`
+	Frame     uint32             `msgpack:"frame,omitempty"`
+	Chunk     int                `msgpack:"chunk,omitempty"`
+	Chunks    int                `msgpack:"chunks,omitempty"`
 }
 
 // Fixtures mirror the M4L preset library in device/scripts/src/main.ts.
@@ -165,6 +173,10 @@ func main() {
 	target := flag.String("target", "localhost:7000", "Server UDP address")
 	sessionID := flag.String("session", "", "Session ID (default: generated from timestamp)")
 	noLock := flag.Bool("no-lock", false, "Skip single-instance lock (for testing/debug)")
+	delta := flag.Bool("delta", false, "Send only changed parameters, with periodic keyframes")
+	keyframe := flag.Duration("keyframe", time.Second, "Keyframe interval for --delta")
+	extra := flag.Int("params", 0, "Extra bulk parameters to send, for testing large sets")
+	maxDatagram := flag.Int("max-datagram", 1400, "Split packets larger than this many bytes into chunks")
 	flag.Parse()
 
 	if !*noLock {
@@ -176,6 +188,10 @@ func main() {
 		*sessionID = fmt.Sprintf("fake-%d", time.Now().UnixMilli())
 	}
 
+	for i := 1; i <= *extra; i++ {
+		allParameters = append(allParameters, fmt.Sprintf("bulk/Param %04d", i))
+	}
+
 	if *mode == "animated" {
 		initAnimated()
 	}
@@ -192,7 +208,11 @@ func main() {
 	defer conn.Close()
 
 	log.Printf("Fake emitter running — mode=%s target=%s session=%s", *mode, *target, *sessionID)
-	log.Printf("Parameters: %v", allParameters)
+	if *extra > 0 {
+		log.Printf("Parameters: %d", len(allParameters))
+	} else {
+		log.Printf("Parameters: %v", allParameters)
+	}
 	log.Printf("Press Ctrl+C to stop")
 
 	ticker := time.NewTicker(40 * time.Millisecond)
@@ -202,6 +222,10 @@ func main() {
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 
 	start := time.Now()
+	var seq uint32
+	var prev map[string]float64
+	var lastKeyframe time.Time
+	var frame uint32
 
 	for {
 		select {
@@ -216,13 +240,28 @@ func main() {
 				Ts:        t.UnixMilli(),
 				State:     state,
 			}
-			data, err := msgpack.Marshal(pkt)
+			if *delta {
+				seq++
+				if seq == 0 {
+					seq = 1
+				}
+				pkt.Seq = seq
+				if prev != nil && t.Sub(lastKeyframe) < *keyframe {
+					pkt.State, pkt.Delta = changed(prev, state), true
+				} else {
+					lastKeyframe = t
+				}
+				prev = state
+			}
+			datagrams, err := encode(pkt, *maxDatagram, &frame)
 			if err != nil {
 				log.Printf("marshal error: %v", err)
 				continue
 			}
-			if _, err := conn.Write(data); err != nil {
-				log.Printf("send error: %v", err)
+			for _, data := range datagrams {
+				if _, err := conn.Write(data); err != nil {
+					log.Printf("send error: %v", err)
+				}
 			}
 		}
 	}
@@ -257,6 +296,64 @@ func buildState(mode string, elapsed float64) map[string]float64 {
 	return state
 }
 
+// encode marshals pkt as one datagram, or if that is larger than max as
+// the fewest chunks of a new frame that each fit.
+func encode(pkt StatePacket, max int, frame *uint32) ([][]byte, error) {
+	data, err := msgpack.Marshal(pkt)
+	if err != nil || len(data) <= max {
+		return [][]byte{data}, err
+	}
+
+	keys := make([]string, 0, len(pkt.State))
+	for k := range pkt.State {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	*frame++
+	for n := (len(data) + max - 1) / max; n <= len(keys); n++ {
+		datagrams, ok, err := split(pkt, keys, n, max, *frame)
+		if err != nil || ok {
+			return datagrams, err
+		}
+	}
+	return nil, fmt.Errorf("a single parameter doesn't fit in %d bytes", max)
+}
+
+// split marshals pkt as n chunks of frame, reporting false if one of them
+// is larger than max.
+func split(pkt StatePacket, keys []string, n, max int, frame uint32) ([][]byte, bool, error) {
+	datagrams := make([][]byte, 0, n)
+	per := (len(keys) + n - 1) / n
+	for i := 0; i < n; i++ {
+		c := pkt
+		c.Frame, c.Chunk, c.Chunks = frame, i, n
+		c.State = make(map[string]float64, per)
+		for _, k := range keys[min(i*per, len(keys)):min((i+1)*per, len(keys))] {
+			c.State[k] = pkt.State[k]
+		}
+		data, err := msgpack.Marshal(c)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(data) > max {
+			return nil, false, nil
+		}
+		datagrams = append(datagrams, data)
+	}
+	return datagrams, true, nil
+}
+
+// changed returns the parameters in next that differ from prev.
+func changed(prev, next map[string]float64) map[string]float64 {
+	out := make(map[string]float64)
+	for p, v := range next {
+		if old, ok := prev[p]; !ok || old != v {
+			out[p] = v
+		}
+	}
+	return out
+}
+
 // Scene represents a scripted sequence of states (future JSON replay mode).
 // Defined here so the structure is established even before the feature is built.
 type Scene struct {
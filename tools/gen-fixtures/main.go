@@ -14,11 +14,12 @@ import (
 )
 
 type fixture struct {
-	Name         string   `json:"name"`
-	ShortName    string   `json:"shortName"`
-	Manufacturer string   `json:"manufacturer"`
-	ChannelCount int      `json:"channelCount"`
-	Channels     []string `json:"channels"`
+	Name         string         `json:"name"`
+	ShortName    string         `json:"shortName"`
+	Manufacturer string         `json:"manufacturer"`
+	ChannelCount int            `json:"channelCount"`
+	Channels     []string       `json:"channels"`
+	Defaults     map[string]int `json:"defaults"`
 }
 
 func main() {
@@ -49,6 +50,14 @@ func main() {
 		if f.ChannelCount != len(f.Channels) {
 			return fmt.Errorf("%s: channelCount %d != len(channels) %d", path, f.ChannelCount, len(f.Channels))
 		}
+		for ch, v := range f.Defaults {
+			if !contains(f.Channels, ch) {
+				return fmt.Errorf("%s: default for unknown channel %q", path, ch)
+			}
+			if v < 0 || v > 255 {
+				return fmt.Errorf("%s: default %d for %q out of range 0-255", path, v, ch)
+			}
+		}
 
 		// Key is relative path without .json extension, e.g. "generic/rgb-3ch"
 		rel, _ := filepath.Rel(fixturesDir, path)
@@ -72,11 +81,12 @@ func main() {
 	b.WriteString("package fixtures\n\n")
 	b.WriteString("// Fixture represents a single fixture at a single channel count.\n")
 	b.WriteString("type Fixture struct {\n")
-	b.WriteString("\tName         string   `json:\"name\"`\n")
-	b.WriteString("\tShortName    string   `json:\"shortName\"`\n")
-	b.WriteString("\tManufacturer string   `json:\"manufacturer\"`\n")
-	b.WriteString("\tChannelCount int      `json:\"channelCount\"`\n")
-	b.WriteString("\tChannels     []string `json:\"channels\"`\n")
+	b.WriteString("\tName         string         `json:\"name\"`\n")
+	b.WriteString("\tShortName    string         `json:\"shortName\"`\n")
+	b.WriteString("\tManufacturer string         `json:\"manufacturer\"`\n")
+	b.WriteString("\tChannelCount int            `json:\"channelCount\"`\n")
+	b.WriteString("\tChannels     []string       `json:\"channels\"`\n")
+	b.WriteString("\tDefaults     map[string]int `json:\"defaults,omitempty\"` // channel name -> DMX value held when no parameter drives it\n")
 	b.WriteString("}\n\n")
 	b.WriteString("// Library maps fixture keys (e.g. \"generic/rgb-3ch\") to their definitions.\n")
 	b.WriteString("var Library = map[string]Fixture{\n")
@@ -96,6 +106,17 @@ func main() {
 			b.WriteString(fmt.Sprintf("%q", ch))
 		}
 		b.WriteString("},\n")
+		if len(f.Defaults) > 0 {
+			// Channel order, so the output is stable.
+			var pairs []string
+			for _, ch := range f.Channels {
+				if v, ok := f.Defaults[ch]; ok {
+					pairs = append(pairs, fmt.Sprintf("%q: %d", ch, v))
+				}
+			}
+			b.WriteString("\t\tDefaults:     map[string]int{" + strings.Join(pairs, ", "))
+			b.WriteString("},\n")
+		}
 		b.WriteString("\t},\n")
 	}
 
@@ -113,3 +134,12 @@ func main() {
 
 	fmt.Printf("Generated %s with %d fixtures\n", outFile, len(keys))
 }
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
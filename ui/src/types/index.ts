@@ -2,6 +2,7 @@
 import type {
   UniverseConfig,
   ParameterConfig,
+  ColorParamConfig,
 } from '@penumbra/protocol-types'
 
 export type {
@@ -10,9 +11,11 @@ export type {
   DiffMessage,
   UniverseStatus,
   StatusMessage,
+  Scene,
   ServerMessage,
   UniverseConfig,
   ParameterConfig,
+  ColorParamConfig,
   Patch,
   SetConfigMessage,
   HotkeyMessage,
@@ -28,6 +31,7 @@ export interface Fixture {
   manufacturer: string
   channelCount: number
   channels: string[]
+  defaults?: Record<string, number>  // channel name → DMX value held when unmapped
 }
 
 export type ConnectionState = 'connecting' | 'connected' | 'disconnected'
@@ -36,6 +40,64 @@ export type ConnectionState = 'connecting' | 'connected' | 'disconnected'
 export interface AppConfig {
   universes: Record<string, UniverseConfig>
   parameters: Record<string, ParameterConfig>
-  emitter?: { idle_timeout_s: number; disconnect_timeout_s: number }
+  emitter?: EmitterConfig
   blackout_scene?: Record<string, number>
+  smoothing?: SmoothingConfig
+  fades?: { blackout_ms: number; reset_ms: number; session_ms: number }
+  submasters?: Record<string, { patches?: string[]; parameters?: string[] }>
+  colors?: Record<string, ColorParamConfig>
+  cues?: CueConfig
+  osc?: OSCConfig
+}
+
+export interface OSCConfig {
+  port?: number        // 0 or unset disables OSC input
+  prefix?: string      // default '/penumbra'
+  session_id?: string  // default 'osc'
+}
+
+export interface CueConfig {
+  merge?: 'htp' | 'ltp'        // default 'htp'
+  lists?: Record<string, Cue[]>
+}
+
+export interface Cue {
+  label?: string
+  scene: string                // stored scene name
+  fade_in_ms?: number
+  fade_out_ms?: number
+  delay_ms?: number
+  follow_ms?: number           // set to auto-follow after the fades complete
+}
+
+export interface EmitterConfig {
+  idle_timeout_s: number
+  disconnect_timeout_s: number
+  stale_ms?: number                      // default 1000
+  failsafe?: FailsafeConfig
+  merge?: 'htp' | 'ltp' | 'priority'     // default 'htp'
+  sources?: Record<string, EmitterSource> // keyed by "ip:port" or "ip"
+}
+
+export interface EmitterSource {
+  label?: string
+  namespace?: string  // prefixes the emitter's parameters as "<namespace>.<param>"
+  priority?: number   // for merge 'priority', default 100
+}
+
+export interface FailsafeConfig {
+  action: 'hold' | 'blackout' | 'scene'  // default 'hold'
+  after: 'idle' | 'disconnected'         // default 'disconnected'
+  scene?: string                         // stored scene, for 'scene'
+  fade_ms: number
+  restore_ms: number
+}
+
+export type SmoothingMode = 'none' | 'linear' | 'exponential'
+
+export interface SmoothingConfig {
+  jitter_ms: number                         // playback delay behind the emitter, default 0
+  mode: SmoothingMode                       // default 'none'
+  time_constant_ms: number                  // 'exponential' time constant, default 50
+  parameters?: Record<string, SmoothingMode> // per-parameter override of mode
 }